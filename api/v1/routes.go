@@ -2,13 +2,67 @@ package v1
 
 import (
 	"learning-cards/internal/handlers"
+	"learning-cards/internal/middleware"
+	"learning-cards/internal/services"
 
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterRoutes(r *gin.Engine, userWordHandler *handlers.UserWordHandler) {
-	r.GET("/v1/words/daily", userWordHandler.GetUserWordDueToday)
-	r.GET("/v1/words/category/:category", userWordHandler.GetUserWordsByCategory)
-	r.PUT("/v1/words/update/:wordID", userWordHandler.UpdateUserWord)
+// reviewRoutes can be called with review-only API keys
+var reviewRoutes = []string{
+	"/v1/words/update/:wordID",
+	"/v1/words/:wordID/answer",
+}
+
+func RegisterRoutes(
+	r *gin.Engine,
+	authService *services.AuthService,
+	apiKeyService *services.APIKeyService,
+	userService *services.UserService,
+	authHandler *handlers.AuthHandler,
+	apiKeyHandler *handlers.APIKeyHandler,
+	userWordHandler *handlers.UserWordHandler,
+	wordHandler *handlers.WordHandler,
+	deckHandler *handlers.DeckHandler,
+	mediaHandler *handlers.MediaHandler,
+	importHandler *handlers.ImportHandler,
+	exportHandler *handlers.ExportHandler,
+	backupHandler *handlers.BackupHandler,
+) {
+	r.POST("/v1/auth/register", authHandler.Register)
+	r.POST("/v1/auth/login", authHandler.Login)
+	r.POST("/v1/auth/refresh", authHandler.Refresh)
+
+	v1 := r.Group("/v1", middleware.Authenticate(authService, apiKeyService, reviewRoutes...))
+	v1.POST("/auth/logout", authHandler.Logout)
+	v1.GET("/api-keys", apiKeyHandler.GetAPIKeys)
+	v1.POST("/api-keys", apiKeyHandler.CreateAPIKey)
+	v1.DELETE("/api-keys/:keyID", apiKeyHandler.RevokeAPIKey)
+	v1.GET("/words/daily", userWordHandler.GetUserWordDueToday)
+	v1.GET("/words/category/:category", userWordHandler.GetUserWordsByCategory)
+	v1.PUT("/words/update/:wordID", userWordHandler.UpdateUserWord)
+	v1.POST("/words/:wordID/answer", userWordHandler.AnswerWord)
+	v1.GET("/words/:wordID/history", userWordHandler.GetReviewHistory)
+
+	v1.GET("/words", wordHandler.GetWords)
+	v1.GET("/words/:wordID", wordHandler.GetWord)
+	v1.GET("/words/:wordID/media", mediaHandler.GetWordMedia)
+	v1.GET("/media/:name", mediaHandler.ServeMedia)
+	v1.GET("/export/anki", exportHandler.ExportAnki)
+	v1.GET("/decks", deckHandler.GetDecks)
+
+	// Words, media and decks are shared by all users, only admins change them
+	editor := v1.Group("", middleware.RequireAdmin(userService))
+	editor.POST("/words", wordHandler.CreateWord)
+	editor.PATCH("/words/:wordID", wordHandler.UpdateWord)
+	editor.DELETE("/words/:wordID", wordHandler.DeleteWord)
+	editor.POST("/words/:wordID/media", mediaHandler.UploadMedia)
+	editor.DELETE("/media/:mediaID", mediaHandler.DeleteMedia)
+	editor.POST("/import/anki", importHandler.ImportAnki)
+	editor.POST("/import/csv", importHandler.ImportCSV)
+	editor.PATCH("/decks/:deckID", deckHandler.UpdateDeck)
 
+	admin := v1.Group("/admin", middleware.RequireAdmin(userService))
+	admin.GET("/backup", backupHandler.Backup)
+	admin.POST("/restore", backupHandler.Restore)
 }
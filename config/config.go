@@ -1,7 +1,10 @@
 package config
 
 import (
+	"log"
 	"os"
+	"strconv"
+	"time"
 )
 
 type DBConfig struct {
@@ -12,6 +15,33 @@ type DBConfig struct {
 	SSLMode  string
 }
 
+type SchedulerConfig struct {
+	Algorithm       string
+	TargetRetention float64
+	FSRSWeights     string
+}
+
+type AuthConfig struct {
+	AccessTokenTTL    time.Duration
+	RefreshTokenTTL   time.Duration
+	AllowRegistration bool
+	SecureCookies     bool
+}
+
+type DeckConfig struct {
+	SourceLanguage string
+	TargetLanguage string
+}
+
+type MediaConfig struct {
+	Directory string
+	MaxSize   int64
+}
+
+type ImportConfig struct {
+	MaxCollectionSize int64
+}
+
 type AppConfig struct {
 	Hostname   string
 	HostnameIP string
@@ -35,9 +65,106 @@ func LoadDBConfig() DBConfig {
 	}
 }
 
+// LoadSchedulerConfig selects the spaced-repetition algorithm ("leitner", "sm2"
+// or "fsrs") and the FSRS parameters.
+func LoadSchedulerConfig() SchedulerConfig {
+	return SchedulerConfig{
+		Algorithm:       getEnv("SCHEDULER_ALGORITHM", "leitner"),
+		TargetRetention: getEnvFloat("FSRS_TARGET_RETENTION", 0.9),
+		FSRSWeights:     getEnv("FSRS_WEIGHTS", ""),
+	}
+}
+
+// LoadAuthConfig configures session lifetimes and who may register.
+func LoadAuthConfig() AuthConfig {
+	return AuthConfig{
+		AccessTokenTTL:    getEnvDuration("AUTH_ACCESS_TOKEN_TTL", time.Hour),
+		RefreshTokenTTL:   getEnvDuration("AUTH_REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		AllowRegistration: getEnvBool("AUTH_ALLOW_REGISTRATION", false),
+		SecureCookies:     getEnvBool("AUTH_SECURE_COOKIES", false),
+	}
+}
+
+// LoadDeckConfig holds the languages given to decks created from categories.
+func LoadDeckConfig() DeckConfig {
+	return DeckConfig{
+		SourceLanguage: getEnv("DECK_SOURCE_LANGUAGE", "es"),
+		TargetLanguage: getEnv("DECK_TARGET_LANGUAGE", "de"),
+	}
+}
+
+// LoadMediaConfig sets where uploaded images and audio are stored and how
+// large they may be, in bytes.
+func LoadMediaConfig() MediaConfig {
+	return MediaConfig{
+		Directory: getEnv("MEDIA_DIR", "media"),
+		MaxSize:   getEnvInt64("MEDIA_MAX_SIZE", 5<<20),
+	}
+}
+
+// LoadImportConfig limits the size of the collection extracted from an
+// uploaded Anki package, in bytes.
+func LoadImportConfig() ImportConfig {
+	return ImportConfig{
+		MaxCollectionSize: getEnvInt64("IMPORT_MAX_COLLECTION_SIZE", 1<<30),
+	}
+}
+
 func getEnv(key, defaultValue string) string {
 	if value, exists := os.LookupEnv(key); exists {
 		return value
 	}
 	return defaultValue
 }
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("invalid value %q for %s, using %g", value, key, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+func getEnvInt64(key string, defaultValue int64) int64 {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		log.Printf("invalid value %q for %s, using %d", value, key, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("invalid value %q for %s, using %s", value, key, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("invalid value %q for %s, using %t", value, key, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
@@ -0,0 +1,155 @@
+package anki_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"learning-cards/internal/anki"
+)
+
+// maxCollectionSize is well above the size of the test collections
+const maxCollectionSize = 1 << 20
+
+func TestReadPackageFile(t *testing.T) {
+	collection, err := anki.ReadPackageFile("testdata/spanish.apkg", maxCollectionSize)
+	if err != nil {
+		t.Fatalf("ReadPackageFile returned error: %v", err)
+	}
+	if len(collection.Notes) != 6 {
+		t.Fatalf("expected 6 notes, got %d", len(collection.Notes))
+	}
+
+	perro := collection.Notes[0]
+	if perro.NoteType != "Basic (and reversed card)" || perro.Cloze || perro.Deck != "Spanish::Animals" {
+		t.Fatalf("unexpected note: %+v", perro)
+	}
+	if front, ok := perro.Field("front"); !ok || front != "el <b>perro</b>" {
+		t.Fatalf("expected the front field by name, got %q", front)
+	}
+	if len(perro.Tags) != 1 || perro.Tags[0] != "animals" {
+		t.Fatalf("unexpected tags: %v", perro.Tags)
+	}
+	if len(perro.Cards) != 2 || perro.Cards[1].Ord != 1 {
+		t.Fatalf("expected a front and a reverse card, got %+v", perro.Cards)
+	}
+	// The manual reschedule is not an answer
+	reviews := perro.Cards[0].Reviews
+	if len(reviews) != 3 || reviews[0].Ease != 3 || reviews[2].Ease != 4 {
+		t.Fatalf("unexpected reviews: %+v", reviews)
+	}
+	if !reviews[0].At.Before(reviews[1].At) || reviews[0].Duration.Seconds() != 4 {
+		t.Fatalf("unexpected review times: %+v", reviews)
+	}
+
+	if !collection.Notes[4].Cloze {
+		t.Fatalf("expected the cloze note type to be recognised")
+	}
+}
+
+func TestReadPackageInvalid(t *testing.T) {
+	if _, err := anki.ReadPackageFile("anki_test.go", maxCollectionSize); err == nil {
+		t.Fatalf("expected an error for a file that is not a package")
+	}
+}
+
+func TestReadPackageTooLarge(t *testing.T) {
+	_, err := anki.ReadPackageFile("testdata/spanish.apkg", 1<<10)
+	if !errors.Is(err, anki.ErrInvalidCollection) {
+		t.Fatalf("expected ErrInvalidCollection for a collection above the limit, got %v", err)
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	cases := map[string]string{
+		"el <b>perro</b>":            "el perro",
+		"der Hund[sound:hund.mp3]":   "der Hund",
+		"la&nbsp;gata":               "la gata",
+		"die Katze<br>die Mieze":     "die Katze die Mieze",
+		"<div>rojo</div><div></div>": "rojo",
+		"Tom &amp; Jerry":            "Tom & Jerry",
+	}
+	for field, want := range cases {
+		if got := anki.PlainText(field); got != want {
+			t.Fatalf("PlainText(%q) = %q, want %q", field, got, want)
+		}
+	}
+}
+
+func TestWritePackageRoundTrip(t *testing.T) {
+	due := time.Now().Add(72 * time.Hour).Truncate(time.Second)
+	reviewed := due.Add(-10 * 24 * time.Hour)
+	collection := &anki.Collection{Notes: []anki.Note{
+		{
+			GUID:   "lc-word-1",
+			Deck:   "Spanish::Animals",
+			Tags:   []string{"noun"},
+			Fields: []anki.Field{{Value: "el perro"}, {Value: "der Hund & Co"}},
+			Cards: []anki.Card{
+				{Ord: 0, Due: due, Interval: 7, Factor: 2300, Reps: 3, Lapses: 1, Reviews: []anki.Review{
+					{At: reviewed, Ease: 1, Duration: 2 * time.Second},
+					{At: reviewed, Ease: 3, Duration: time.Second, Interval: 7, LastInterval: 1},
+				}},
+				{Ord: 1},
+			},
+		},
+		{Deck: "", Fields: []anki.Field{{Value: "la gata"}, {Value: "die Katze"}}, Cards: []anki.Card{{Ord: 0}}},
+	}}
+
+	var buf bytes.Buffer
+	if err := anki.WritePackage(&buf, collection); err != nil {
+		t.Fatalf("WritePackage returned error: %v", err)
+	}
+	read, err := anki.ReadPackage(bytes.NewReader(buf.Bytes()), int64(buf.Len()), maxCollectionSize)
+	if err != nil {
+		t.Fatalf("ReadPackage returned error: %v", err)
+	}
+	if len(read.Notes) != 2 {
+		t.Fatalf("expected 2 notes, got %d", len(read.Notes))
+	}
+
+	perro := read.Notes[0]
+	if perro.GUID != "lc-word-1" || perro.Deck != "Spanish::Animals" || perro.NoteType != "Basic (and reversed card)" {
+		t.Fatalf("unexpected note: %+v", perro)
+	}
+	if back, _ := perro.Field("Back"); anki.PlainText(back) != "der Hund & Co" {
+		t.Fatalf("expected the back field to survive, got %q", back)
+	}
+	if len(perro.Cards) != 2 {
+		t.Fatalf("expected 2 cards, got %d", len(perro.Cards))
+	}
+	card := perro.Cards[0]
+	// Review cards are due on a day, not at a time
+	if card.Interval != 7 || card.Factor != 2300 || card.Reps != 3 || card.Lapses != 1 ||
+		card.Due.After(due) || due.Sub(card.Due) >= 24*time.Hour {
+		t.Fatalf("unexpected scheduling state: %+v", card)
+	}
+	if len(card.Reviews) != 2 || card.Reviews[0].At.Equal(card.Reviews[1].At) || card.Reviews[1].Interval != 7 {
+		t.Fatalf("unexpected reviews: %+v", card.Reviews)
+	}
+	if !perro.Cards[1].Due.IsZero() {
+		t.Fatalf("expected the reverse card to be new, got %+v", perro.Cards[1])
+	}
+	if read.Notes[1].Deck != "Default" {
+		t.Fatalf("expected notes without deck in the default deck, got %q", read.Notes[1].Deck)
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	collection := &anki.Collection{Notes: []anki.Note{
+		{Deck: "food", Tags: []string{"noun", "fruit"}, Fields: []anki.Field{{Value: "la naranja"}, {Value: "die Orange\tdie Apfelsine"}}},
+	}}
+	var buf bytes.Buffer
+	if err := anki.WriteText(&buf, collection); err != nil {
+		t.Fatalf("WriteText returned error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !strings.HasPrefix(lines[0], "#separator:tab") {
+		t.Fatalf("expected the separator header, got %q", lines[0])
+	}
+	if last := lines[len(lines)-1]; last != "la naranja\tdie Orange die Apfelsine\tfood\tnoun fruit" {
+		t.Fatalf("unexpected row %q", last)
+	}
+}
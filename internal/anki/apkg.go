@@ -0,0 +1,95 @@
+package anki
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Collection files of a package, newest first. collection.anki21b is
+// compressed with zstd and cannot be read; packages exported with "Support
+// older Anki versions" also hold a collection.anki21.
+const (
+	collectionV21  = "collection.anki21"
+	collectionV21b = "collection.anki21b"
+	collectionV2   = "collection.anki2"
+)
+
+var ErrUnsupportedPackage = errors.New("unsupported Anki package, export it with \"Support older Anki versions\" enabled")
+
+// ReadPackage reads the collection of a .apkg file. Collections that extract
+// to more than maxCollectionSize bytes are rejected, the sizes in the zip
+// headers cannot be trusted.
+func ReadPackage(r io.ReaderAt, size, maxCollectionSize int64) (*Collection, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCollection, err)
+	}
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	file, ok := files[collectionV21]
+	if !ok {
+		if _, ok := files[collectionV21b]; ok {
+			// The collection.anki2 next to it only asks to update Anki
+			return nil, ErrUnsupportedPackage
+		}
+		if file, ok = files[collectionV2]; !ok {
+			return nil, fmt.Errorf("%w: no collection in package", ErrInvalidCollection)
+		}
+	}
+
+	// SQLite needs a file to open
+	path, err := extract(file, maxCollectionSize)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(path)
+	return ReadCollection(path)
+}
+
+// ReadPackageFile reads the collection of the .apkg file at path
+func ReadPackageFile(path string, maxCollectionSize int64) (*Collection, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return ReadPackage(file, info.Size(), maxCollectionSize)
+}
+
+// extract copies a collection to a temporary file, at most maxSize bytes of it
+func extract(file *zip.File, maxSize int64) (string, error) {
+	src, err := file.Open()
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidCollection, err)
+	}
+	defer src.Close()
+
+	dst, err := os.CreateTemp("", "anki-*.sqlite")
+	if err != nil {
+		return "", err
+	}
+	n, err := io.Copy(dst, io.LimitReader(src, maxSize+1))
+	if err == nil && n > maxSize {
+		err = fmt.Errorf("collection larger than %d bytes", maxSize)
+	}
+	if err != nil {
+		dst.Close()
+		os.Remove(dst.Name())
+		return "", fmt.Errorf("%w: %v", ErrInvalidCollection, err)
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(dst.Name())
+		return "", err
+	}
+	return dst.Name(), nil
+}
@@ -0,0 +1,258 @@
+// Package anki reads and writes Anki deck packages (.apkg), zip files holding
+// the SQLite collection of the notes, cards and review log.
+package anki
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fieldSeparator separates the fields of a note
+const fieldSeparator = "\x1f"
+
+// modelTypeCloze marks note types whose cards are generated from cloze deletions
+const modelTypeCloze = 1
+
+// Card types, learning cards are due at a timestamp, review cards on a day
+// counted from the creation of the collection
+const (
+	cardTypeNew        = 0
+	cardTypeLearning   = 1
+	cardTypeReview     = 2
+	cardTypeRelearning = 3
+)
+
+// day is the unit of the due days and intervals of review cards
+const day = 24 * time.Hour
+
+// revlogTypeManual marks review log entries written when a card was
+// rescheduled by hand, they are not answers
+const revlogTypeManual = 4
+
+var ErrInvalidCollection = errors.New("invalid Anki collection")
+
+// Collection holds the notes of an Anki collection
+type Collection struct {
+	Notes []Note
+}
+
+// Note is an Anki note with its fields in the order of its note type. Notes
+// have no deck of their own, Deck is the deck of their first card.
+type Note struct {
+	ID       int64
+	GUID     string
+	NoteType string
+	Cloze    bool
+	Fields   []Field
+	Tags     []string
+	Deck     string
+	Cards    []Card
+}
+
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Card is one of the cards generated from a note, Ord is the index of the card
+// template, so 0 is the front and 1 the reverse card of the reversed note types.
+// Due is zero for cards that were never studied.
+type Card struct {
+	ID       int64
+	Ord      int
+	Due      time.Time
+	Interval int // Days
+	Factor   int // Ease factor in permille
+	Reps     int
+	Lapses   int
+	Reviews  []Review
+}
+
+// Review is an answer from the review log, Ease is the button that was pressed
+// from 1 (again) to 4 (easy). Intervals are in days, 0 while learning.
+type Review struct {
+	At           time.Time
+	Ease         int
+	Duration     time.Duration
+	Interval     int
+	LastInterval int
+}
+
+// Field returns the value of the field with the given name, ignoring case
+func (n Note) Field(name string) (string, bool) {
+	for _, field := range n.Fields {
+		if strings.EqualFold(field.Name, name) {
+			return field.Value, true
+		}
+	}
+	return "", false
+}
+
+type noteType struct {
+	Name   string `json:"name"`
+	Type   int    `json:"type"`
+	Fields []struct {
+		Name string `json:"name"`
+		Ord  int    `json:"ord"`
+	} `json:"flds"`
+}
+
+type deck struct {
+	Name string `json:"name"`
+}
+
+// ReadCollection reads the notes, cards and reviews of a collection file
+func ReadCollection(path string) (*Collection, error) {
+	db, err := gorm.Open(sqlite.Open("file:"+path+"?mode=ro"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	defer sqlDB.Close()
+
+	var col struct {
+		Crt    int64
+		Models string
+		Decks  string
+	}
+	if err := db.Raw("SELECT crt, models, decks FROM col").Scan(&col).Error; err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCollection, err)
+	}
+	var noteTypes map[string]noteType
+	if err := json.Unmarshal([]byte(col.Models), &noteTypes); err != nil {
+		return nil, fmt.Errorf("%w: note types: %v", ErrInvalidCollection, err)
+	}
+	var decks map[string]deck
+	if err := json.Unmarshal([]byte(col.Decks), &decks); err != nil {
+		return nil, fmt.Errorf("%w: decks: %v", ErrInvalidCollection, err)
+	}
+
+	var noteRows []struct {
+		ID   int64
+		GUID string
+		Mid  int64
+		Flds string
+		Tags string
+	}
+	if err := db.Raw("SELECT id, guid, mid, flds, tags FROM notes ORDER BY id").Scan(&noteRows).Error; err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCollection, err)
+	}
+	var cardRows []struct {
+		ID     int64
+		Nid    int64
+		Did    int64
+		Ord    int
+		Type   int
+		Due    int64
+		Ivl    int
+		Factor int
+		Reps   int
+		Lapses int
+	}
+	if err := db.Raw("SELECT id, nid, did, ord, type, due, ivl, factor, reps, lapses FROM cards ORDER BY nid, ord").Scan(&cardRows).Error; err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCollection, err)
+	}
+	var reviewRows []struct {
+		ID      int64
+		Cid     int64
+		Ease    int
+		Ivl     int
+		LastIvl int
+		Time    int64
+		Type    int
+	}
+	if err := db.Raw("SELECT id, cid, ease, ivl, lastIvl, time, type FROM revlog ORDER BY id").Scan(&reviewRows).Error; err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCollection, err)
+	}
+
+	reviews := make(map[int64][]Review)
+	for _, row := range reviewRows {
+		if row.Type == revlogTypeManual || row.Ease < 1 || row.Ease > 4 {
+			continue
+		}
+		reviews[row.Cid] = append(reviews[row.Cid], Review{
+			At:           time.UnixMilli(row.ID),
+			Ease:         row.Ease,
+			Duration:     time.Duration(row.Time) * time.Millisecond,
+			Interval:     days(row.Ivl),
+			LastInterval: days(row.LastIvl),
+		})
+	}
+
+	collection := &Collection{Notes: make([]Note, 0, len(noteRows))}
+	index := make(map[int64]int, len(noteRows))
+	for _, row := range noteRows {
+		model := noteTypes[fmt.Sprint(row.Mid)]
+		values := strings.Split(row.Flds, fieldSeparator)
+		note := Note{
+			ID:       row.ID,
+			GUID:     row.GUID,
+			NoteType: model.Name,
+			Cloze:    model.Type == modelTypeCloze,
+			Fields:   make([]Field, len(values)),
+			Tags:     strings.Fields(row.Tags),
+		}
+		for i, value := range values {
+			note.Fields[i].Value = value
+		}
+		for _, field := range model.Fields {
+			if field.Ord >= 0 && field.Ord < len(note.Fields) {
+				note.Fields[field.Ord].Name = field.Name
+			}
+		}
+		index[row.ID] = len(collection.Notes)
+		collection.Notes = append(collection.Notes, note)
+	}
+	for _, row := range cardRows {
+		i, ok := index[row.Nid]
+		if !ok {
+			continue
+		}
+		note := &collection.Notes[i]
+		if note.Deck == "" {
+			note.Deck = decks[fmt.Sprint(row.Did)].Name
+		}
+		note.Cards = append(note.Cards, Card{
+			ID:       row.ID,
+			Ord:      row.Ord,
+			Due:      dueTime(time.Unix(col.Crt, 0), row.Type, row.Due),
+			Interval: days(row.Ivl),
+			Factor:   row.Factor,
+			Reps:     row.Reps,
+			Lapses:   row.Lapses,
+			Reviews:  reviews[row.ID],
+		})
+	}
+	return collection, nil
+}
+
+// dueTime converts the due column of a card. Learning cards are due at a
+// timestamp, except in day based learning steps that share the review format.
+func dueTime(created time.Time, cardType int, due int64) time.Time {
+	switch cardType {
+	case cardTypeLearning, cardTypeRelearning:
+		if due > 1e9 {
+			return time.Unix(due, 0)
+		}
+		return created.Add(time.Duration(due) * day)
+	case cardTypeReview:
+		return created.Add(time.Duration(due) * day)
+	default:
+		return time.Time{}
+	}
+}
+
+// days converts an interval, negative intervals are learning steps in seconds
+func days(interval int) int {
+	return max(interval, 0)
+}
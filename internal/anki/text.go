@@ -0,0 +1,23 @@
+package anki
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	soundTag  = regexp.MustCompile(`\[sound:[^\]]*\]`)
+	lineBreak = regexp.MustCompile(`(?i)<br\s*/?>|</?(div|p)\b[^>]*>`)
+	htmlTag   = regexp.MustCompile(`<[^>]*>`)
+)
+
+// PlainText turns the HTML of a field into plain text: tags and sound
+// references are removed, entities decoded and whitespace collapsed
+func PlainText(field string) string {
+	text := soundTag.ReplaceAllString(field, " ")
+	text = lineBreak.ReplaceAllString(text, " ")
+	text = htmlTag.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+	return strings.Join(strings.Fields(text), " ")
+}
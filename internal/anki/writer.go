@@ -0,0 +1,355 @@
+package anki
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Ids of the note type, deck and deck options written to packages. Anki keeps
+// note types with the same id and fields, so imports do not pile up copies.
+const (
+	basicReversedID = 1700000000001
+	defaultDeckID   = 1
+	defaultConfID   = 1
+)
+
+// Card queues, matching the card types for cards that are not suspended
+const (
+	queueNew    = 0
+	queueReview = 2
+)
+
+// Review log types
+const (
+	revlogTypeLearn  = 0
+	revlogTypeReview = 1
+)
+
+// defaultFactor is the ease factor Anki gives new cards, in permille
+const defaultFactor = 2500
+
+// schema is the collection layout of Anki 2.1 up to 2.1.49, which every later
+// version can still import
+const schema = `
+CREATE TABLE col (id integer primary key, crt integer not null, mod integer not null, scm integer not null, ver integer not null, dty integer not null, usn integer not null, ls integer not null, conf text not null, models text not null, decks text not null, dconf text not null, tags text not null);
+CREATE TABLE notes (id integer primary key, guid text not null, mid integer not null, mod integer not null, usn integer not null, tags text not null, flds text not null, sfld integer not null, csum integer not null, flags integer not null, data text not null);
+CREATE TABLE cards (id integer primary key, nid integer not null, did integer not null, ord integer not null, mod integer not null, usn integer not null, type integer not null, queue integer not null, due integer not null, ivl integer not null, factor integer not null, reps integer not null, lapses integer not null, left integer not null, odue integer not null, odid integer not null, flags integer not null, data text not null);
+CREATE TABLE revlog (id integer primary key, cid integer not null, usn integer not null, ease integer not null, ivl integer not null, lastIvl integer not null, factor integer not null, time integer not null, type integer not null);
+CREATE TABLE graves (usn integer not null, oid integer not null, type integer not null);
+CREATE INDEX ix_notes_usn on notes (usn);
+CREATE INDEX ix_cards_usn on cards (usn);
+CREATE INDEX ix_revlog_usn on revlog (usn);
+CREATE INDEX ix_cards_nid on cards (nid);
+CREATE INDEX ix_cards_sched on cards (did, queue, due);
+CREATE INDEX ix_revlog_cid on revlog (cid);
+CREATE INDEX ix_notes_csum on notes (csum);
+`
+
+// WritePackage writes the notes as a .apkg package. Every note is written with
+// the "Basic (and reversed card)" note type from its first two fields, which
+// are taken as plain text. Cards keep their scheduling state and reviews.
+func WritePackage(w io.Writer, collection *Collection) error {
+	file, err := os.CreateTemp("", "anki-*.sqlite")
+	if err != nil {
+		return err
+	}
+	path := file.Name()
+	file.Close()
+	defer os.Remove(path)
+
+	if err := writeCollection(path, collection, time.Now()); err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(w)
+	dst, err := archive.Create(collectionV2)
+	if err != nil {
+		return err
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	if _, err := io.Copy(dst, src); err != nil {
+		return err
+	}
+	// The package carries no media files
+	media, err := archive.Create("media")
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(media, "{}"); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+func writeCollection(path string, collection *Collection, now time.Time) error {
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		return err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	defer sqlDB.Close()
+
+	if err := db.Exec(schema).Error; err != nil {
+		return err
+	}
+
+	created := collectionCreated(collection, now)
+	// Ids are millisecond timestamps in Anki, so they are made unique from now on
+	nextID := now.UnixMilli()
+	newID := func() int64 {
+		nextID++
+		return nextID
+	}
+
+	deckIDs := map[string]int64{"": defaultDeckID}
+	decks := map[string]any{fmt.Sprint(defaultDeckID): deckJSON(defaultDeckID, "Default", now)}
+	for _, note := range collection.Notes {
+		if _, ok := deckIDs[note.Deck]; !ok {
+			id := newID()
+			deckIDs[note.Deck] = id
+			decks[fmt.Sprint(id)] = deckJSON(id, note.Deck, now)
+		}
+	}
+
+	reviewIDs := make(map[int64]bool)
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("INSERT INTO col VALUES (1, ?, ?, ?, 11, 0, 0, 0, ?, ?, ?, ?, '{}')",
+			created.Unix(), now.UnixMilli(), now.UnixMilli(),
+			mustJSON(colConf), mustJSON(map[string]any{fmt.Sprint(basicReversedID): basicReversedJSON(now)}),
+			mustJSON(decks), mustJSON(map[string]any{fmt.Sprint(defaultConfID): deckConf}),
+		).Error; err != nil {
+			return err
+		}
+
+		for i, note := range collection.Notes {
+			front, back := noteSide(note, 0), noteSide(note, 1)
+			guid := note.GUID
+			if guid == "" {
+				guid = fmt.Sprintf("lc%d", newID())
+			}
+			noteID := newID()
+			tags := ""
+			if len(note.Tags) > 0 {
+				tags = " " + strings.Join(note.Tags, " ") + " "
+			}
+			if err := tx.Exec("INSERT INTO notes VALUES (?, ?, ?, ?, -1, ?, ?, ?, ?, 0, '')",
+				noteID, guid, basicReversedID, now.Unix(), tags,
+				html.EscapeString(front)+fieldSeparator+html.EscapeString(back), front, checksum(front),
+			).Error; err != nil {
+				return err
+			}
+
+			for _, card := range note.Cards {
+				cardID := newID()
+				if err := writeCard(tx, cardID, noteID, deckIDs[note.Deck], i+1, card, created, now); err != nil {
+					return err
+				}
+				for _, review := range card.Reviews {
+					// Review ids are their timestamps and must be unique
+					reviewID := review.At.UnixMilli()
+					for reviewIDs[reviewID] {
+						reviewID++
+					}
+					reviewIDs[reviewID] = true
+					reviewType := revlogTypeReview
+					if review.LastInterval == 0 {
+						reviewType = revlogTypeLearn
+					}
+					if err := tx.Exec("INSERT INTO revlog VALUES (?, ?, -1, ?, ?, ?, ?, ?, ?)",
+						reviewID, cardID, review.Ease, review.Interval, review.LastInterval,
+						cardFactor(card), review.Duration.Milliseconds(), reviewType,
+					).Error; err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// WriteText writes the notes as tab separated text that Anki imports into the
+// "Basic (and reversed card)" note type, with the deck and tags in the third
+// and fourth column. Text files cannot carry scheduling state.
+func WriteText(w io.Writer, collection *Collection) error {
+	header := "#separator:tab\n#html:false\n#notetype:Basic (and reversed card)\n#deck column:3\n#tags column:4\n"
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+	for _, note := range collection.Notes {
+		columns := []string{noteSide(note, 0), noteSide(note, 1), note.Deck, strings.Join(note.Tags, " ")}
+		for i, column := range columns {
+			columns[i] = textCell.Replace(column)
+		}
+		if _, err := io.WriteString(w, strings.Join(columns, "\t")+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// textCell keeps a value on its line and in its column
+var textCell = strings.NewReplacer("\t", " ", "\r\n", " ", "\n", " ", "\r", " ")
+
+// writeCard writes a card as new, or as review card due on the day of Due
+func writeCard(tx *gorm.DB, cardID, noteID, deckID int64, position int, card Card, created, now time.Time) error {
+	if card.Due.IsZero() {
+		return tx.Exec("INSERT INTO cards VALUES (?, ?, ?, ?, ?, -1, ?, ?, ?, 0, 0, 0, 0, 0, 0, 0, 0, '')",
+			cardID, noteID, deckID, card.Ord, now.Unix(), cardTypeNew, queueNew, position).Error
+	}
+	due := int64(card.Due.Sub(created) / day)
+	return tx.Exec("INSERT INTO cards VALUES (?, ?, ?, ?, ?, -1, ?, ?, ?, ?, ?, ?, ?, 0, 0, 0, 0, '')",
+		cardID, noteID, deckID, card.Ord, now.Unix(), cardTypeReview, queueReview, due,
+		max(card.Interval, 1), cardFactor(card), card.Reps, card.Lapses).Error
+}
+
+// collectionCreated is the start of the day of the earliest due card, so that
+// due days are never negative
+func collectionCreated(collection *Collection, now time.Time) time.Time {
+	created := now
+	for _, note := range collection.Notes {
+		for _, card := range note.Cards {
+			if !card.Due.IsZero() && card.Due.Before(created) {
+				created = card.Due
+			}
+		}
+	}
+	return created.UTC().Truncate(day)
+}
+
+func cardFactor(card Card) int {
+	if card.Factor > 0 {
+		return card.Factor
+	}
+	return defaultFactor
+}
+
+func noteSide(note Note, position int) string {
+	if position < len(note.Fields) {
+		return note.Fields[position].Value
+	}
+	return ""
+}
+
+// checksum is the number Anki uses to find duplicates of the first field
+func checksum(field string) int64 {
+	sum := sha1.Sum([]byte(field))
+	return int64(binary.BigEndian.Uint32(sum[:4]))
+}
+
+func mustJSON(value any) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		panic(err)
+	}
+	return string(encoded)
+}
+
+var colConf = map[string]any{
+	"activeDecks":   []int{defaultDeckID},
+	"curDeck":       defaultDeckID,
+	"newSpread":     0,
+	"collapseTime":  1200,
+	"timeLim":       0,
+	"estTimes":      true,
+	"dueCounts":     true,
+	"curModel":      nil,
+	"nextPos":       1,
+	"sortType":      "noteFld",
+	"sortBackwards": false,
+	"addToCur":      true,
+}
+
+var deckConf = map[string]any{
+	"id":       defaultConfID,
+	"name":     "Default",
+	"mod":      0,
+	"usn":      0,
+	"maxTaken": 60,
+	"autoplay": true,
+	"timer":    0,
+	"replayq":  true,
+	"dyn":      false,
+	"new": map[string]any{
+		"bury": true, "delays": []int{1, 10}, "initialFactor": defaultFactor,
+		"ints": []int{1, 4, 7}, "order": 1, "perDay": 20, "separate": true,
+	},
+	"rev": map[string]any{
+		"bury": true, "ease4": 1.3, "fuzz": 0.05, "ivlFct": 1,
+		"maxIvl": 36500, "minSpace": 1, "perDay": 200,
+	},
+	"lapse": map[string]any{
+		"delays": []int{10}, "leechAction": 0, "leechFails": 8, "minInt": 1, "mult": 0,
+	},
+}
+
+func deckJSON(id int64, name string, now time.Time) map[string]any {
+	return map[string]any{
+		"id":        id,
+		"name":      name,
+		"desc":      "",
+		"conf":      defaultConfID,
+		"dyn":       0,
+		"collapsed": false,
+		"extendNew": 10,
+		"extendRev": 50,
+		"mod":       now.Unix(),
+		"usn":       -1,
+		"newToday":  []int{0, 0},
+		"revToday":  []int{0, 0},
+		"lrnToday":  []int{0, 0},
+		"timeToday": []int{0, 0},
+	}
+}
+
+func basicReversedJSON(now time.Time) map[string]any {
+	field := func(name string, ord int) map[string]any {
+		return map[string]any{
+			"name": name, "ord": ord, "sticky": false, "rtl": false,
+			"font": "Arial", "size": 20, "media": []string{},
+		}
+	}
+	template := func(name string, ord int, question, answer string) map[string]any {
+		return map[string]any{
+			"name": name, "ord": ord, "qfmt": question,
+			"afmt": "{{FrontSide}}\n\n<hr id=answer>\n\n" + answer,
+			"did":  nil, "bqfmt": "", "bafmt": "",
+		}
+	}
+	return map[string]any{
+		"id":        basicReversedID,
+		"name":      "Basic (and reversed card)",
+		"type":      0,
+		"mod":       now.Unix(),
+		"usn":       -1,
+		"sortf":     0,
+		"did":       defaultDeckID,
+		"tags":      []string{},
+		"vers":      []string{},
+		"flds":      []any{field("Front", 0), field("Back", 1)},
+		"tmpls":     []any{template("Card 1", 0, "{{Front}}", "{{Back}}"), template("Card 2", 1, "{{Back}}", "{{Front}}")},
+		"req":       []any{[]any{0, "any", []int{0}}, []any{1, "any", []int{1}}},
+		"css":       ".card {\n font-family: arial;\n font-size: 20px;\n text-align: center;\n color: black;\n background-color: white;\n}\n",
+		"latexPre":  "\\documentclass[12pt]{article}\n\\special{papersize=3in,5in}\n\\usepackage[utf8]{inputenc}\n\\usepackage{amssymb,amsmath}\n\\pagestyle{empty}\n\\setlength{\\parindent}{0in}\n\\begin{document}\n",
+		"latexPost": "\\end{document}",
+	}
+}
@@ -0,0 +1,170 @@
+// Package answer checks typed answers against the expected side of a card.
+package answer
+
+import (
+	"strings"
+	"unicode"
+
+	"learning-cards/internal/models"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Verdict is the outcome of comparing an answer with the expected text.
+type Verdict string
+
+const (
+	VerdictCorrect Verdict = "correct"
+	VerdictAlmost  Verdict = "almost"
+	VerdictWrong   Verdict = "wrong"
+)
+
+// Grade maps a verdict onto the grade used to reschedule the card: almost
+// correct answers count as recalled with difficulty.
+func (v Verdict) Grade() models.Grade {
+	switch v {
+	case VerdictCorrect:
+		return models.GradeGood
+	case VerdictAlmost:
+		return models.GradeHard
+	}
+	return models.GradeAgain
+}
+
+// Result is the verdict on an answer with a diff from the answer to the
+// expected text.
+type Result struct {
+	Verdict  Verdict   `json:"verdict"`
+	Answer   string    `json:"answer"`
+	Expected string    `json:"expected"`
+	Accepted []string  `json:"accepted"`
+	Distance int       `json:"distance"`
+	Diff     []Segment `json:"diff"`
+}
+
+// MaxLength is the longest answer that is checked, in characters. Checking
+// takes time and memory growing with the length of the answer.
+const MaxLength = 1000
+
+// articles may be left out of an answer. A wrong article still makes the
+// answer almost correct only.
+var articles = map[string]struct{}{
+	"el": {}, "la": {}, "los": {}, "las": {}, "un": {}, "una": {}, "unos": {}, "unas": {},
+	"der": {}, "die": {}, "das": {}, "den": {}, "dem": {}, "des": {},
+	"ein": {}, "eine": {}, "einen": {}, "einem": {}, "einer": {}, "eines": {},
+}
+
+// Check compares a typed answer with the expected text ignoring case,
+// whitespace, accents and a missing article. Answers a few typos away from the
+// expected text are almost correct.
+func Check(typed, expected string) Result {
+	result := Result{
+		Answer:   collapseSpaces(typed),
+		Expected: collapseSpaces(expected),
+		Accepted: []string{expected},
+	}
+	result.Diff = Diff(result.Answer, result.Expected)
+
+	got, want := Normalize(typed), Normalize(expected)
+	gotArticle, gotBody := splitArticle(got)
+	wantArticle, wantBody := splitArticle(want)
+	result.Distance = levenshtein([]rune(got), []rune(want))
+
+	switch {
+	case got == want:
+		result.Verdict = VerdictCorrect
+	case gotBody == wantBody && (gotArticle == "" || wantArticle == ""):
+		result.Verdict = VerdictCorrect
+		result.Distance = 0
+	case gotBody == wantBody:
+		result.Verdict = VerdictAlmost
+	case levenshtein([]rune(gotBody), []rune(wantBody)) <= tolerance(wantBody):
+		result.Verdict = VerdictAlmost
+	default:
+		result.Verdict = VerdictWrong
+	}
+	return result
+}
+
+// CheckAny checks a typed answer against every accepted text and returns the
+// best result: correct before almost correct before wrong, then the closest.
+// Expected holds the text the answer was matched against.
+func CheckAny(typed string, accepted []string) Result {
+	var best Result
+	for i, expected := range accepted {
+		result := Check(typed, expected)
+		if i == 0 || rank(result) < rank(best) ||
+			(rank(result) == rank(best) && result.Distance < best.Distance) {
+			best = result
+		}
+	}
+	best.Accepted = accepted
+	return best
+}
+
+func rank(result Result) int {
+	switch result.Verdict {
+	case VerdictCorrect:
+		return 0
+	case VerdictAlmost:
+		return 1
+	}
+	return 2
+}
+
+// Normalize lowercases s, strips accents and surrounding punctuation and
+// collapses whitespace.
+func Normalize(s string) string {
+	s = strings.ToLower(stripAccents(collapseSpaces(s)))
+	return strings.TrimFunc(s, func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSpace(r)
+	})
+}
+
+// tolerance is the number of typos accepted for an almost correct answer,
+// growing with the length of the expected text.
+func tolerance(expected string) int {
+	return min(len([]rune(expected))/4, 3)
+}
+
+func splitArticle(s string) (string, string) {
+	first, rest, found := strings.Cut(s, " ")
+	if _, ok := articles[first]; found && ok {
+		return first, rest
+	}
+	return "", s
+}
+
+func collapseSpaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func stripAccents(s string) string {
+	stripped, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), s)
+	if err != nil {
+		return s
+	}
+	return stripped
+}
+
+func levenshtein(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,148 @@
+package answer_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"learning-cards/internal/answer"
+	"learning-cards/internal/models"
+)
+
+func TestCheckVerdicts(t *testing.T) {
+	for _, tc := range []struct {
+		typed, expected string
+		want            answer.Verdict
+	}{
+		{"der Hund", "der Hund", answer.VerdictCorrect},
+		{"  DER   hund ", "der Hund", answer.VerdictCorrect},
+		{"Hund", "der Hund", answer.VerdictCorrect},
+		{"el raton", "el ratón", answer.VerdictCorrect},
+		{"Mudigkeit", "die Müdigkeit", answer.VerdictCorrect},
+		{"¿que tal?", "¿Qué tal?", answer.VerdictCorrect},
+		{"die Hund", "der Hund", answer.VerdictAlmost},
+		{"die Kaze", "die Katze", answer.VerdictAlmost},
+		{"Besprechnug", "die Besprechung", answer.VerdictAlmost},
+		{"rat", "rot", answer.VerdictWrong},
+		{"die Maus", "der Hund", answer.VerdictWrong},
+		{"", "der Hund", answer.VerdictWrong},
+	} {
+		if got := answer.Check(tc.typed, tc.expected); got.Verdict != tc.want {
+			t.Fatalf("Check(%q, %q) = %s, want %s", tc.typed, tc.expected, got.Verdict, tc.want)
+		}
+	}
+}
+
+func TestVerdictGrades(t *testing.T) {
+	for verdict, want := range map[answer.Verdict]models.Grade{
+		answer.VerdictCorrect: models.GradeGood,
+		answer.VerdictAlmost:  models.GradeHard,
+		answer.VerdictWrong:   models.GradeAgain,
+	} {
+		if got := verdict.Grade(); got != want {
+			t.Fatalf("%s.Grade() = %s, want %s", verdict, got, want)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	for _, tc := range []struct {
+		typed, expected string
+		want            []answer.Segment
+	}{
+		{"der hund", "der Hund", []answer.Segment{{Op: answer.OpEqual, Text: "der Hund"}}},
+		{"die Kaze", "die Katze", []answer.Segment{
+			{Op: answer.OpEqual, Text: "die Ka"},
+			{Op: answer.OpInsert, Text: "t"},
+			{Op: answer.OpEqual, Text: "ze"},
+		}},
+		{"die Hund", "der Hund", []answer.Segment{
+			{Op: answer.OpEqual, Text: "d"},
+			{Op: answer.OpDelete, Text: "ie"},
+			{Op: answer.OpInsert, Text: "er"},
+			{Op: answer.OpEqual, Text: " Hund"},
+		}},
+		{"Hunde", "Hund", []answer.Segment{
+			{Op: answer.OpEqual, Text: "Hund"},
+			{Op: answer.OpDelete, Text: "e"},
+		}},
+		{"", "rot", []answer.Segment{{Op: answer.OpInsert, Text: "rot"}}},
+		// Too long to compare, replaced as a whole
+		{strings.Repeat("a", 1000), strings.Repeat("a", 300), []answer.Segment{
+			{Op: answer.OpDelete, Text: strings.Repeat("a", 1000)},
+			{Op: answer.OpInsert, Text: strings.Repeat("a", 300)},
+		}},
+	} {
+		if got := answer.Diff(tc.typed, tc.expected); !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("Diff(%q, %q) = %+v, want %+v", tc.typed, tc.expected, got, tc.want)
+		}
+	}
+}
+
+func TestCheckGender(t *testing.T) {
+	for _, tc := range []struct {
+		typed, expected string
+		want            answer.Verdict
+	}{
+		{"der", "der Hund", answer.VerdictCorrect},
+		{" DAS ", "das Pferd", answer.VerdictCorrect},
+		{"feminine", "die Katze", answer.VerdictCorrect},
+		{"eine", "die Katze", answer.VerdictCorrect},
+		{"la", "la gata", answer.VerdictCorrect},
+		{"die", "der Hund", answer.VerdictWrong},
+		{"el", "la gata", answer.VerdictWrong},
+		{"der", "rot", answer.VerdictWrong},
+	} {
+		got := answer.CheckGender(tc.typed, tc.expected)
+		if got.Verdict != tc.want {
+			t.Fatalf("CheckGender(%q, %q) = %s, want %s", tc.typed, tc.expected, got.Verdict, tc.want)
+		}
+	}
+	if got := answer.CheckGender("die", "der Hund"); got.Expected != "der" {
+		t.Fatalf("expected the article as expected answer, got %q", got.Expected)
+	}
+}
+
+func TestCheckAnyPicksBestVariant(t *testing.T) {
+	accepted := []string{"die Orange", "die Apfelsine"}
+	for _, tc := range []struct {
+		typed        string
+		want         answer.Verdict
+		wantExpected string
+	}{
+		{"apfelsine", answer.VerdictCorrect, "die Apfelsine"},
+		{"die Orange", answer.VerdictCorrect, "die Orange"},
+		{"Apfelsin", answer.VerdictAlmost, "die Apfelsine"},
+		{"die Banane", answer.VerdictWrong, "die Orange"},
+	} {
+		got := answer.CheckAny(tc.typed, accepted)
+		if got.Verdict != tc.want || got.Expected != tc.wantExpected {
+			t.Fatalf("CheckAny(%q) = %s against %q, want %s against %q", tc.typed, got.Verdict, got.Expected, tc.want, tc.wantExpected)
+		}
+		if !reflect.DeepEqual(got.Accepted, accepted) {
+			t.Fatalf("expected all accepted variants in the result, got %v", got.Accepted)
+		}
+	}
+}
+
+func TestMakeCloze(t *testing.T) {
+	for _, tc := range []struct {
+		sentence, word     string
+		wantText, wantWord string
+	}{
+		{"Mi perro duerme mucho.", "el perro", "Mi _____ duerme mucho.", "perro"},
+		{"Perros y gatos.", "el perro", "_____ y gatos.", "Perros"},
+		{"Corro cada mañana.", "correr", "_____ cada mañana.", "Corro"},
+		{"Compré tres naranjas.", "la naranja", "Compré tres _____.", "naranjas"},
+		{"Er läuft schnell.", "laufen", "Er _____ schnell.", "läuft"},
+		{"El raton come queso.", "el ratón", "El _____ come queso.", "raton"},
+		{"Usamos un sistema de gestión nuevo.", "el sistema de gestión", "Usamos un _____ nuevo.", "sistema de gestión"},
+		{"Pero no quiero.", "el perro", "", ""},
+		{"La casa es roja.", "el coche", "", ""},
+	} {
+		cloze, ok := answer.MakeCloze(tc.sentence, tc.word)
+		if ok != (tc.wantText != "") || cloze.Text != tc.wantText || cloze.Answer != tc.wantWord {
+			t.Fatalf("MakeCloze(%q, %q) = %+v, %v, want %q hiding %q", tc.sentence, tc.word, cloze, ok, tc.wantText, tc.wantWord)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package answer
+
+import (
+	"unicode"
+
+	"learning-cards/internal/utils"
+)
+
+// Blank replaces the hidden word of a cloze.
+const Blank = "_____"
+
+// Cloze is an example sentence with the studied word blanked out.
+type Cloze struct {
+	Text   string // the sentence with Blank in place of the word
+	Answer string // the hidden word as it occurs in the sentence
+}
+
+// MakeCloze blanks out the occurrence of word in sentence. The occurrence may
+// differ in case and accents and, for single words, be inflected ("corro" for
+// "correr", "Perros" for "el perro"). A leading article of word is ignored.
+// It reports false when the sentence does not contain the word.
+func MakeCloze(sentence, word string) (Cloze, bool) {
+	_, lemma := utils.SplitArticle(word)
+	target := foldRunes([]rune(lemma))
+	text := []rune(sentence)
+	folded := foldRunes(text)
+	if len(target) == 0 {
+		return Cloze{}, false
+	}
+
+	start, end, ok := findPhrase(folded, target)
+	if !ok && !containsSpace(target) {
+		start, end, ok = findInflected(folded, target)
+	}
+	if !ok {
+		return Cloze{}, false
+	}
+	return Cloze{
+		Text:   string(text[:start]) + Blank + string(text[end:]),
+		Answer: string(text[start:end]),
+	}, true
+}
+
+// findPhrase finds target as whole words in text
+func findPhrase(text, target []rune) (int, int, bool) {
+	for start := 0; start+len(target) <= len(text); start++ {
+		end := start + len(target)
+		if (start > 0 && isWordRune(text[start-1])) || (end < len(text) && isWordRune(text[end])) {
+			continue
+		}
+		if string(text[start:end]) == string(target) {
+			return start, end, true
+		}
+	}
+	return 0, 0, false
+}
+
+// infinitiveEndings are dropped from verbs to find their conjugated forms
+var infinitiveEndings = []string{"ar", "er", "ir", "en", "n"}
+
+// maxInflection is the number of letters an inflection may add to a stem
+const maxInflection = 5
+
+// findInflected finds a word of text that inflects target: target itself with
+// an ending (plurals) or, for verbs, the stem of target with an ending. The
+// stem must keep at least three letters. The longest match wins.
+func findInflected(text, target []rune) (int, int, bool) {
+	stems := [][]rune{target}
+	for _, ending := range infinitiveEndings {
+		if stem, ok := trimSuffix(target, []rune(ending)); ok && len(stem) >= 3 {
+			stems = append(stems, stem)
+		}
+	}
+
+	bestStart, bestEnd, bestStem := 0, 0, 0
+	for start := 0; start < len(text); {
+		if !isWordRune(text[start]) {
+			start++
+			continue
+		}
+		end := start
+		for end < len(text) && isWordRune(text[end]) {
+			end++
+		}
+		word := text[start:end]
+		for _, stem := range stems {
+			if len(stem) > bestStem && len(word) <= len(stem)+maxInflection && commonPrefix(word, stem) == len(stem) {
+				bestStart, bestEnd, bestStem = start, end, len(stem)
+			}
+		}
+		start = end
+	}
+	return bestStart, bestEnd, bestStem > 0
+}
+
+func trimSuffix(rs, suffix []rune) ([]rune, bool) {
+	if len(rs) < len(suffix) || string(rs[len(rs)-len(suffix):]) != string(suffix) {
+		return nil, false
+	}
+	return rs[:len(rs)-len(suffix)], true
+}
+
+func commonPrefix(a, b []rune) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+func containsSpace(rs []rune) bool {
+	for _, r := range rs {
+		if unicode.IsSpace(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
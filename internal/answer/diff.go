@@ -0,0 +1,139 @@
+package answer
+
+import "strings"
+
+// Operations of a diff segment
+const (
+	OpEqual  = "equal"  // text the answer got right
+	OpDelete = "delete" // text of the answer that is not expected
+	OpInsert = "insert" // expected text missing from the answer
+)
+
+// Segment is a run of text of a diff.
+type Segment struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// maxDiffCells bounds the table of edit distances built by Diff
+const maxDiffCells = 1 << 18
+
+// Diff returns the edits turning the answer into the expected text, comparing
+// characters ignoring case and accents. Equal segments hold the expected text.
+// Texts too long to compare are shown as replaced as a whole.
+func Diff(typed, expected string) []Segment {
+	a, b := []rune(typed), []rune(expected)
+	if (len(a)+1)*(len(b)+1) > maxDiffCells {
+		var diff diffBuilder
+		for _, r := range a {
+			diff.add(OpDelete, r)
+		}
+		for _, r := range b {
+			diff.add(OpInsert, r)
+		}
+		return diff.segments()
+	}
+	foldedA, foldedB := foldRunes(a), foldRunes(b)
+
+	// distances[i][j] is the edit distance between a[i:] and b[j:]
+	distances := make([][]int, len(a)+1)
+	for i := range distances {
+		distances[i] = make([]int, len(b)+1)
+	}
+	for i := len(a); i >= 0; i-- {
+		for j := len(b); j >= 0; j-- {
+			switch {
+			case i == len(a):
+				distances[i][j] = len(b) - j
+			case j == len(b):
+				distances[i][j] = len(a) - i
+			case foldedA[i] == foldedB[j]:
+				distances[i][j] = distances[i+1][j+1]
+			default:
+				distances[i][j] = 1 + min(distances[i+1][j], distances[i][j+1], distances[i+1][j+1])
+			}
+		}
+	}
+
+	var diff diffBuilder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && foldedA[i] == foldedB[j]:
+			diff.add(OpEqual, b[j])
+			i++
+			j++
+		case i < len(a) && j < len(b) && distances[i][j] == 1+distances[i+1][j+1]:
+			diff.add(OpDelete, a[i])
+			diff.add(OpInsert, b[j])
+			i++
+			j++
+		case i < len(a) && (j == len(b) || distances[i][j] == 1+distances[i+1][j]):
+			diff.add(OpDelete, a[i])
+			i++
+		default:
+			diff.add(OpInsert, b[j])
+			j++
+		}
+	}
+	return diff.segments()
+}
+
+// diffBuilder groups the deleted and inserted characters between two equal
+// runs, so that a changed word reads as one deletion followed by one insertion.
+type diffBuilder struct {
+	done            []Segment
+	equal, del, ins strings.Builder
+}
+
+func (d *diffBuilder) add(op string, r rune) {
+	switch op {
+	case OpEqual:
+		d.flushChanges()
+		d.equal.WriteRune(r)
+	case OpDelete:
+		d.flushEqual()
+		d.del.WriteRune(r)
+	case OpInsert:
+		d.flushEqual()
+		d.ins.WriteRune(r)
+	}
+}
+
+func (d *diffBuilder) flushEqual() {
+	if d.equal.Len() > 0 {
+		d.done = append(d.done, Segment{Op: OpEqual, Text: d.equal.String()})
+		d.equal.Reset()
+	}
+}
+
+func (d *diffBuilder) flushChanges() {
+	if d.del.Len() > 0 {
+		d.done = append(d.done, Segment{Op: OpDelete, Text: d.del.String()})
+		d.del.Reset()
+	}
+	if d.ins.Len() > 0 {
+		d.done = append(d.done, Segment{Op: OpInsert, Text: d.ins.String()})
+		d.ins.Reset()
+	}
+}
+
+func (d *diffBuilder) segments() []Segment {
+	d.flushEqual()
+	d.flushChanges()
+	if d.done == nil {
+		return []Segment{}
+	}
+	return d.done
+}
+
+func foldRunes(rs []rune) []rune {
+	folded := make([]rune, len(rs))
+	for i, r := range rs {
+		folded[i] = r
+		if f := []rune(strings.ToLower(stripAccents(string(r)))); len(f) > 0 {
+			folded[i] = f[0]
+		}
+	}
+	return folded
+}
@@ -0,0 +1,29 @@
+package answer
+
+import (
+	"learning-cards/internal/models"
+	"learning-cards/internal/utils"
+)
+
+// CheckGender checks the article chosen for a noun such as "der Hund". The
+// answer may be any article of the same gender or the name of the gender.
+// There is no almost correct gender.
+func CheckGender(typed, expected string) Result {
+	article, _ := utils.SplitArticle(expected)
+	result := Result{
+		Answer:   collapseSpaces(typed),
+		Expected: article,
+		Accepted: []string{article},
+		Verdict:  VerdictWrong,
+	}
+	result.Diff = Diff(result.Answer, result.Expected)
+
+	gender := utils.ArticleGender(article)
+	got := Normalize(typed)
+	if gender != "" && (utils.ArticleGender(got) == gender || models.Gender(got) == gender) {
+		result.Verdict = VerdictCorrect
+	} else {
+		result.Distance = 1
+	}
+	return result
+}
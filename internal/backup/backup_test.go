@@ -0,0 +1,71 @@
+package backup_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"learning-cards/internal/backup"
+)
+
+func TestWriteAndRead(t *testing.T) {
+	deckID := uint(1)
+	var buf bytes.Buffer
+	writer, err := backup.NewWriter(&buf, time.Now())
+	if err != nil {
+		t.Fatalf("NewWriter returned error: %v", err)
+	}
+	records := []any{
+		backup.User{ID: 1, Username: "ana"},
+		backup.Deck{ID: deckID, Name: "animals", Path: "animals", Slug: "animals"},
+		backup.Word{ID: 1, Word: "el perro", Translations: []string{"der Hund"}, DeckID: &deckID},
+		backup.UserWord{ID: 1, UserID: 1, WordID: 1, CardType: "translation", Direction: "forward"},
+		backup.ReviewLog{ID: 1, UserWordID: 1, Grade: 3},
+	}
+	for _, record := range records {
+		if err := writer.Write(record); err != nil {
+			t.Fatalf("Write returned error: %v", err)
+		}
+	}
+
+	snapshot, err := backup.Read(&buf)
+	if err != nil {
+		t.Fatalf("Read returned error: %v", err)
+	}
+	if snapshot.Header.Version != backup.Version || len(snapshot.Users) != 1 || len(snapshot.Words) != 1 || len(snapshot.ReviewLogs) != 1 {
+		t.Fatalf("unexpected snapshot: %+v", snapshot)
+	}
+	if changes := backup.Diff(snapshot, snapshot); changes.Words.Unchanged != 1 || changes.Words.Updated != 0 {
+		t.Fatalf("expected no changes against itself, got %+v", changes)
+	}
+}
+
+func TestReadRejectsInvalidBackups(t *testing.T) {
+	header := `{"type":"header","data":{"format":"learning-cards-backup","version":1}}` + "\n"
+	cases := map[string]string{
+		"missing header": `{"type":"user","data":{"id":1,"username":"ana"}}`,
+		"unknown format": `{"type":"header","data":{"format":"other","version":1}}`,
+		"unknown type":   header + `{"type":"session","data":{}}`,
+		"unknown field":  header + `{"type":"user","data":{"id":1,"username":"ana","role":"admin"}}`,
+		"duplicate id":   header + `{"type":"user","data":{"id":1,"username":"ana"}}` + "\n" + `{"type":"user","data":{"id":1,"username":"ben"}}`,
+		"unknown deck":   header + `{"type":"word","data":{"id":1,"word":"rojo","translations":["rot"],"deck_id":7}}`,
+		"empty word":     header + `{"type":"word","data":{"id":1,"word":"","translations":["rot"]}}`,
+		"unknown user":   header + `{"type":"word","data":{"id":1,"word":"rojo","translations":["rot"]}}` + "\n" + `{"type":"user_word","data":{"id":1,"user_id":3,"word_id":1,"card_type":"translation","direction":"forward"}}`,
+		"invalid json":   header + `{"type":`,
+		"invalid grade":  header + `{"type":"review_log","data":{"id":1,"user_word_id":1,"grade":"perfect"}}`,
+		"second header":  header + header,
+		"invalid card":   header + `{"type":"user","data":{"id":1,"username":"ana"}}` + "\n" + `{"type":"word","data":{"id":1,"word":"rojo","translations":["rot"]}}` + "\n" + `{"type":"user_word","data":{"id":1,"user_id":1,"word_id":1,"card_type":"spelling","direction":"forward"}}`,
+	}
+	for name, input := range cases {
+		if _, err := backup.Read(strings.NewReader(input)); !errors.Is(err, backup.ErrInvalidBackup) {
+			t.Fatalf("%s: expected ErrInvalidBackup, got %v", name, err)
+		}
+	}
+
+	newer := `{"type":"header","data":{"format":"learning-cards-backup","version":2}}`
+	if _, err := backup.Read(strings.NewReader(newer)); !errors.Is(err, backup.ErrUnsupportedVersion) {
+		t.Fatalf("expected ErrUnsupportedVersion, got %v", err)
+	}
+}
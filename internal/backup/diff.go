@@ -0,0 +1,66 @@
+package backup
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// Change counts the records of a type a restore creates, updates and deletes
+type Change struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Deleted   int `json:"deleted"`
+	Unchanged int `json:"unchanged"`
+}
+
+// Changes are what restoring a backup does to the collection
+type Changes struct {
+	Users      Change `json:"users"`
+	Decks      Change `json:"decks"`
+	Words      Change `json:"words"`
+	UserWords  Change `json:"user_words"`
+	ReviewLogs Change `json:"review_logs"`
+}
+
+// Diff compares the current collection with a backup, records being matched by id
+func Diff(current, restored *Snapshot) Changes {
+	return Changes{
+		Users:      diff(current.Users, restored.Users, func(r User) uint { return r.ID }),
+		Decks:      diff(current.Decks, restored.Decks, func(r Deck) uint { return r.ID }),
+		Words:      diff(current.Words, restored.Words, func(r Word) uint { return r.ID }),
+		UserWords:  diff(current.UserWords, restored.UserWords, func(r UserWord) uint { return r.ID }),
+		ReviewLogs: diff(current.ReviewLogs, restored.ReviewLogs, func(r ReviewLog) uint { return r.ID }),
+	}
+}
+
+func diff[T any](current, restored []T, id func(T) uint) Change {
+	existing := make(map[uint]T, len(current))
+	for _, record := range current {
+		existing[id(record)] = record
+	}
+	var change Change
+	for _, record := range restored {
+		old, ok := existing[id(record)]
+		switch {
+		case !ok:
+			change.Created++
+		case equal(old, record):
+			change.Unchanged++
+		default:
+			change.Updated++
+		}
+		delete(existing, id(record))
+	}
+	change.Deleted = len(existing)
+	return change
+}
+
+// equal compares records by their encoding, so times are compared as written
+func equal(a, b any) bool {
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return reflect.DeepEqual(a, b)
+	}
+	return string(encodedA) == string(encodedB)
+}
@@ -0,0 +1,333 @@
+// Package backup defines the versioned NDJSON backup format of the collection:
+// a header line followed by one record per line for every user, deck, word,
+// user word and review log.
+package backup
+
+import (
+	"learning-cards/internal/models"
+	"time"
+)
+
+// Format names the backup format in the header, Version is the version
+// written and the only one restored
+const (
+	Format  = "learning-cards-backup"
+	Version = 1
+)
+
+// Record types, in the order they are written
+const (
+	TypeHeader    = "header"
+	TypeUser      = "user"
+	TypeDeck      = "deck"
+	TypeWord      = "word"
+	TypeUserWord  = "user_word"
+	TypeReviewLog = "review_log"
+)
+
+// Header is the first line of a backup
+type Header struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// User keeps the password hash so that accounts keep working after a restore.
+// Sessions and API keys are not backed up.
+type User struct {
+	ID           uint      `json:"id"`
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"password_hash,omitempty"`
+	IsAdmin      bool      `json:"is_admin"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type Deck struct {
+	ID             uint      `json:"id"`
+	ParentID       *uint     `json:"parent_id"`
+	Name           string    `json:"name"`
+	Path           string    `json:"path"`
+	Slug           string    `json:"slug"`
+	Description    string    `json:"description"`
+	SourceLanguage string    `json:"source_language"`
+	TargetLanguage string    `json:"target_language"`
+	ReverseCards   bool      `json:"reverse_cards"`
+	GenderCards    bool      `json:"gender_cards"`
+	ClozeCards     bool      `json:"cloze_cards"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Word holds its accepted translations, primary first, and the metadata of its
+// media. Media files are not part of the backup.
+type Word struct {
+	ID                 uint          `json:"id"`
+	Word               string        `json:"word"`
+	Translations       []string      `json:"translations"`
+	Category           string        `json:"category"`
+	Gender             models.Gender `json:"gender"`
+	TranslationGender  models.Gender `json:"translation_gender"`
+	ExampleSentence    string        `json:"example_sentence"`
+	ExampleTranslation string        `json:"example_translation"`
+	Note               string        `json:"note"`
+	PartOfSpeech       string        `json:"part_of_speech"`
+	DeckID             *uint         `json:"deck_id"`
+	SourceFile         string        `json:"source_file,omitempty"`
+	SourceKey          string        `json:"source_key,omitempty"`
+	RetiredAt          *time.Time    `json:"retired_at,omitempty"`
+	CreatedAt          time.Time     `json:"created_at"`
+	Media              []Media       `json:"media"`
+}
+
+type Media struct {
+	ID        uint      `json:"id"`
+	Kind      string    `json:"kind"`
+	MimeType  string    `json:"mime_type"`
+	Hash      string    `json:"hash"`
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	Filename  string    `json:"filename"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type UserWord struct {
+	ID                uint             `json:"id"`
+	UserID            uint             `json:"user_id"`
+	WordID            uint             `json:"word_id"`
+	CardType          models.CardType  `json:"card_type"`
+	Direction         models.Direction `json:"direction"`
+	BoxNumber         uint             `json:"box_number"`
+	LastReview        time.Time        `json:"last_review"`
+	NextReview        time.Time        `json:"next_review"`
+	CorrectAttempts   uint             `json:"correct_attempts"`
+	IncorrectAttempts uint             `json:"incorrect_attempts"`
+	EaseFactor        float64          `json:"ease_factor"`
+	Repetitions       uint             `json:"repetitions"`
+	IntervalDays      uint             `json:"interval_days"`
+	Stability         float64          `json:"stability"`
+	Difficulty        float64          `json:"difficulty"`
+}
+
+type ReviewLog struct {
+	ID                   uint         `json:"id"`
+	UserWordID           uint         `json:"user_word_id"`
+	ReviewedAt           time.Time    `json:"reviewed_at"`
+	Grade                models.Grade `json:"grade"`
+	PreviousBox          uint         `json:"previous_box"`
+	PreviousIntervalDays uint         `json:"previous_interval_days"`
+	NewBox               uint         `json:"new_box"`
+	NewIntervalDays      uint         `json:"new_interval_days"`
+	ElapsedDays          float64      `json:"elapsed_days"`
+	ResponseTimeMs       uint         `json:"response_time_ms"`
+}
+
+// Times are written in UTC so that equal rows have equal records
+func utc(t time.Time) time.Time {
+	return t.UTC()
+}
+
+func utcPtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+func FromUser(user models.User) User {
+	return User{
+		ID:           user.ID,
+		Username:     user.Username,
+		PasswordHash: user.PasswordHash,
+		IsAdmin:      user.IsAdmin,
+		CreatedAt:    utc(user.CreatedAt),
+	}
+}
+
+func (u User) Model() models.User {
+	return models.User{
+		ID:           u.ID,
+		Username:     u.Username,
+		PasswordHash: u.PasswordHash,
+		IsAdmin:      u.IsAdmin,
+		CreatedAt:    u.CreatedAt,
+	}
+}
+
+func FromDeck(deck models.Deck) Deck {
+	return Deck{
+		ID:             deck.ID,
+		ParentID:       deck.ParentID,
+		Name:           deck.Name,
+		Path:           deck.Path,
+		Slug:           deck.Slug,
+		Description:    deck.Description,
+		SourceLanguage: deck.SourceLanguage,
+		TargetLanguage: deck.TargetLanguage,
+		ReverseCards:   deck.ReverseCards,
+		GenderCards:    deck.GenderCards,
+		ClozeCards:     deck.ClozeCards,
+		CreatedAt:      utc(deck.CreatedAt),
+	}
+}
+
+func (d Deck) Model() models.Deck {
+	return models.Deck{
+		ID:             d.ID,
+		ParentID:       d.ParentID,
+		Name:           d.Name,
+		Path:           d.Path,
+		Slug:           d.Slug,
+		Description:    d.Description,
+		SourceLanguage: d.SourceLanguage,
+		TargetLanguage: d.TargetLanguage,
+		ReverseCards:   d.ReverseCards,
+		GenderCards:    d.GenderCards,
+		ClozeCards:     d.ClozeCards,
+		CreatedAt:      d.CreatedAt,
+	}
+}
+
+// FromWord converts a word loaded with its translations and media
+func FromWord(word models.Word) Word {
+	record := Word{
+		ID:                 word.ID,
+		Word:               word.Word,
+		Translations:       word.AcceptedTranslations(),
+		Category:           word.Category,
+		Gender:             word.Gender,
+		TranslationGender:  word.TranslationGender,
+		ExampleSentence:    word.ExampleSentence,
+		ExampleTranslation: word.ExampleTranslation,
+		Note:               word.Note,
+		PartOfSpeech:       word.PartOfSpeech,
+		DeckID:             word.DeckID,
+		SourceFile:         word.SourceFile,
+		SourceKey:          word.SourceKey,
+		RetiredAt:          utcPtr(word.RetiredAt),
+		CreatedAt:          utc(word.CreatedAt),
+		Media:              make([]Media, len(word.Media)),
+	}
+	for i, media := range word.Media {
+		record.Media[i] = Media{
+			ID:        media.ID,
+			Kind:      media.Kind,
+			MimeType:  media.MimeType,
+			Hash:      media.Hash,
+			Name:      media.Name,
+			Size:      media.Size,
+			Filename:  media.Filename,
+			CreatedAt: utc(media.CreatedAt),
+		}
+	}
+	return record
+}
+
+// Model converts the word together with its translations and media
+func (w Word) Model() models.Word {
+	word := models.Word{
+		ID:                 w.ID,
+		Word:               w.Word,
+		Category:           w.Category,
+		Gender:             w.Gender,
+		TranslationGender:  w.TranslationGender,
+		ExampleSentence:    w.ExampleSentence,
+		ExampleTranslation: w.ExampleTranslation,
+		Note:               w.Note,
+		PartOfSpeech:       w.PartOfSpeech,
+		DeckID:             w.DeckID,
+		SourceFile:         w.SourceFile,
+		SourceKey:          w.SourceKey,
+		RetiredAt:          w.RetiredAt,
+		CreatedAt:          w.CreatedAt,
+	}
+	if len(w.Translations) > 0 {
+		word.SetTranslations(w.Translations[0], w.Translations[1:])
+	}
+	for i := range word.Translations {
+		word.Translations[i].WordID = w.ID
+	}
+	for _, media := range w.Media {
+		word.Media = append(word.Media, models.Media{
+			ID:        media.ID,
+			WordID:    w.ID,
+			Kind:      media.Kind,
+			MimeType:  media.MimeType,
+			Hash:      media.Hash,
+			Name:      media.Name,
+			Size:      media.Size,
+			Filename:  media.Filename,
+			CreatedAt: media.CreatedAt,
+		})
+	}
+	return word
+}
+
+func FromUserWord(userWord models.UserWord) UserWord {
+	return UserWord{
+		ID:                userWord.ID,
+		UserID:            userWord.UserID,
+		WordID:            userWord.WordID,
+		CardType:          userWord.CardType,
+		Direction:         userWord.Direction,
+		BoxNumber:         userWord.BoxNumber,
+		LastReview:        utc(userWord.LastReview),
+		NextReview:        utc(userWord.NextReview),
+		CorrectAttempts:   userWord.CorrectAttempts,
+		IncorrectAttempts: userWord.IncorrectAttempts,
+		EaseFactor:        userWord.EaseFactor,
+		Repetitions:       userWord.Repetitions,
+		IntervalDays:      userWord.IntervalDays,
+		Stability:         userWord.Stability,
+		Difficulty:        userWord.Difficulty,
+	}
+}
+
+func (u UserWord) Model() models.UserWord {
+	return models.UserWord{
+		ID:                u.ID,
+		UserID:            u.UserID,
+		WordID:            u.WordID,
+		CardType:          u.CardType,
+		Direction:         u.Direction,
+		BoxNumber:         u.BoxNumber,
+		LastReview:        u.LastReview,
+		NextReview:        u.NextReview,
+		CorrectAttempts:   u.CorrectAttempts,
+		IncorrectAttempts: u.IncorrectAttempts,
+		EaseFactor:        u.EaseFactor,
+		Repetitions:       u.Repetitions,
+		IntervalDays:      u.IntervalDays,
+		Stability:         u.Stability,
+		Difficulty:        u.Difficulty,
+	}
+}
+
+func FromReviewLog(reviewLog models.ReviewLog) ReviewLog {
+	return ReviewLog{
+		ID:                   reviewLog.ID,
+		UserWordID:           reviewLog.UserWordID,
+		ReviewedAt:           utc(reviewLog.ReviewedAt),
+		Grade:                reviewLog.Grade,
+		PreviousBox:          reviewLog.PreviousBox,
+		PreviousIntervalDays: reviewLog.PreviousIntervalDays,
+		NewBox:               reviewLog.NewBox,
+		NewIntervalDays:      reviewLog.NewIntervalDays,
+		ElapsedDays:          reviewLog.ElapsedDays,
+		ResponseTimeMs:       reviewLog.ResponseTimeMs,
+	}
+}
+
+func (r ReviewLog) Model() models.ReviewLog {
+	return models.ReviewLog{
+		ID:                   r.ID,
+		UserWordID:           r.UserWordID,
+		ReviewedAt:           r.ReviewedAt,
+		Grade:                r.Grade,
+		PreviousBox:          r.PreviousBox,
+		PreviousIntervalDays: r.PreviousIntervalDays,
+		NewBox:               r.NewBox,
+		NewIntervalDays:      r.NewIntervalDays,
+		ElapsedDays:          r.ElapsedDays,
+		ResponseTimeMs:       r.ResponseTimeMs,
+	}
+}
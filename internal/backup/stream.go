@@ -0,0 +1,191 @@
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// maxLineSize bounds a single record, words with long notes and many media included
+const maxLineSize = 4 << 20
+
+var (
+	ErrInvalidBackup      = errors.New("invalid backup")
+	ErrUnsupportedVersion = errors.New("unsupported backup version")
+)
+
+// line is the envelope of every record
+type line struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// Writer writes a backup, one record per line
+type Writer struct {
+	encoder *json.Encoder
+}
+
+// NewWriter writes the header and returns a writer for the records
+func NewWriter(w io.Writer, createdAt time.Time) (*Writer, error) {
+	writer := &Writer{encoder: json.NewEncoder(w)}
+	header := Header{Format: Format, Version: Version, CreatedAt: utc(createdAt)}
+	if err := writer.write(TypeHeader, header); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+// Write writes a User, Deck, Word, UserWord or ReviewLog record
+func (w *Writer) Write(record any) error {
+	recordType, err := typeOf(record)
+	if err != nil {
+		return err
+	}
+	return w.write(recordType, record)
+}
+
+func (w *Writer) write(recordType string, record any) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return w.encoder.Encode(line{Type: recordType, Data: data})
+}
+
+func typeOf(record any) (string, error) {
+	switch record.(type) {
+	case User:
+		return TypeUser, nil
+	case Deck:
+		return TypeDeck, nil
+	case Word:
+		return TypeWord, nil
+	case UserWord:
+		return TypeUserWord, nil
+	case ReviewLog:
+		return TypeReviewLog, nil
+	}
+	return "", fmt.Errorf("no backup record type for %T", record)
+}
+
+// Snapshot holds every record of a backup
+type Snapshot struct {
+	Header     Header
+	Users      []User
+	Decks      []Deck
+	Words      []Word
+	UserWords  []UserWord
+	ReviewLogs []ReviewLog
+}
+
+// Write adds a record to the snapshot, so a snapshot can be filled like a backup is written
+func (s *Snapshot) Write(record any) error {
+	switch record := record.(type) {
+	case User:
+		s.Users = append(s.Users, record)
+	case Deck:
+		s.Decks = append(s.Decks, record)
+	case Word:
+		s.Words = append(s.Words, record)
+	case UserWord:
+		s.UserWords = append(s.UserWords, record)
+	case ReviewLog:
+		s.ReviewLogs = append(s.ReviewLogs, record)
+	default:
+		return fmt.Errorf("no backup record type for %T", record)
+	}
+	return nil
+}
+
+// Read reads and validates a backup. The header must come first and name a
+// supported version, every record must be well formed and refer only to
+// records of the same backup.
+func Read(r io.Reader) (*Snapshot, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxLineSize)
+	snapshot := &Snapshot{}
+	number := 0
+	for scanner.Scan() {
+		number++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record line
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidBackup, number, err)
+		}
+		if snapshot.Header.Format == "" {
+			if err := readHeader(record, &snapshot.Header); err != nil {
+				return nil, fmt.Errorf("line %d: %w", number, err)
+			}
+			continue
+		}
+		if err := snapshot.read(record); err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidBackup, number, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidBackup, number+1, err)
+	}
+	if snapshot.Header.Format == "" {
+		return nil, fmt.Errorf("%w: missing header", ErrInvalidBackup)
+	}
+	if err := snapshot.validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	return snapshot, nil
+}
+
+func readHeader(record line, header *Header) error {
+	if record.Type != TypeHeader {
+		return fmt.Errorf("%w: expected the header, got %q", ErrInvalidBackup, record.Type)
+	}
+	if err := json.Unmarshal(record.Data, header); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	if header.Format != Format {
+		return fmt.Errorf("%w: unknown format %q", ErrInvalidBackup, header.Format)
+	}
+	if header.Version != Version {
+		return fmt.Errorf("%w: %d, want %d", ErrUnsupportedVersion, header.Version, Version)
+	}
+	return nil
+}
+
+func (s *Snapshot) read(record line) error {
+	var err error
+	switch record.Type {
+	case TypeUser:
+		err = decodeInto(record.Data, &s.Users)
+	case TypeDeck:
+		err = decodeInto(record.Data, &s.Decks)
+	case TypeWord:
+		err = decodeInto(record.Data, &s.Words)
+	case TypeUserWord:
+		err = decodeInto(record.Data, &s.UserWords)
+	case TypeReviewLog:
+		err = decodeInto(record.Data, &s.ReviewLogs)
+	case TypeHeader:
+		err = errors.New("second header")
+	default:
+		err = fmt.Errorf("unknown record type %q", record.Type)
+	}
+	return err
+}
+
+// decodeInto decodes a record and appends it. Unknown fields are rejected,
+// they would be lost on restore.
+func decodeInto[T any](data json.RawMessage, records *[]T) error {
+	var record T
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&record); err != nil {
+		return err
+	}
+	*records = append(*records, record)
+	return nil
+}
@@ -0,0 +1,93 @@
+package backup
+
+import (
+	"fmt"
+	"learning-cards/internal/models"
+)
+
+// validate checks ids and the references between records
+func (s *Snapshot) validate() error {
+	users := make(map[uint]bool, len(s.Users))
+	usernames := make(map[string]bool, len(s.Users))
+	for _, user := range s.Users {
+		if user.ID == 0 || users[user.ID] {
+			return fmt.Errorf("user %q: missing or duplicate id %d", user.Username, user.ID)
+		}
+		if user.Username == "" || usernames[user.Username] {
+			return fmt.Errorf("user %d: empty or duplicate username %q", user.ID, user.Username)
+		}
+		users[user.ID], usernames[user.Username] = true, true
+	}
+
+	decks := make(map[uint]bool, len(s.Decks))
+	slugs := make(map[string]bool, len(s.Decks))
+	for _, deck := range s.Decks {
+		if deck.ID == 0 || decks[deck.ID] {
+			return fmt.Errorf("deck %q: missing or duplicate id %d", deck.Path, deck.ID)
+		}
+		if deck.Slug == "" || slugs[deck.Slug] {
+			return fmt.Errorf("deck %d: empty or duplicate slug %q", deck.ID, deck.Slug)
+		}
+		decks[deck.ID], slugs[deck.Slug] = true, true
+	}
+	for _, deck := range s.Decks {
+		if deck.ParentID != nil && !decks[*deck.ParentID] {
+			return fmt.Errorf("deck %d: unknown parent deck %d", deck.ID, *deck.ParentID)
+		}
+	}
+
+	words := make(map[uint]bool, len(s.Words))
+	media := make(map[uint]bool)
+	for _, word := range s.Words {
+		if word.ID == 0 || words[word.ID] {
+			return fmt.Errorf("word %q: missing or duplicate id %d", word.Word, word.ID)
+		}
+		if word.Word == "" || len(word.Translations) == 0 || word.Translations[0] == "" {
+			return fmt.Errorf("word %d: word and translation must not be empty", word.ID)
+		}
+		if word.DeckID != nil && !decks[*word.DeckID] {
+			return fmt.Errorf("word %d: unknown deck %d", word.ID, *word.DeckID)
+		}
+		for _, item := range word.Media {
+			if item.ID == 0 || media[item.ID] {
+				return fmt.Errorf("word %d: missing or duplicate media id %d", word.ID, item.ID)
+			}
+			media[item.ID] = true
+		}
+		words[word.ID] = true
+	}
+
+	userWords := make(map[uint]bool, len(s.UserWords))
+	cards := make(map[string]bool, len(s.UserWords))
+	for _, userWord := range s.UserWords {
+		if userWord.ID == 0 || userWords[userWord.ID] {
+			return fmt.Errorf("user word: missing or duplicate id %d", userWord.ID)
+		}
+		if !users[userWord.UserID] || !words[userWord.WordID] {
+			return fmt.Errorf("user word %d: unknown user %d or word %d", userWord.ID, userWord.UserID, userWord.WordID)
+		}
+		if _, err := models.ParseCard(userWord.WordID, string(userWord.CardType), string(userWord.Direction)); err != nil || userWord.CardType == "" || userWord.Direction == "" {
+			return fmt.Errorf("user word %d: invalid card %q %q", userWord.ID, userWord.CardType, userWord.Direction)
+		}
+		card := fmt.Sprint(userWord.UserID, userWord.WordID, userWord.CardType, userWord.Direction)
+		if cards[card] {
+			return fmt.Errorf("user word %d: duplicate card", userWord.ID)
+		}
+		userWords[userWord.ID], cards[card] = true, true
+	}
+
+	reviewLogs := make(map[uint]bool, len(s.ReviewLogs))
+	for _, reviewLog := range s.ReviewLogs {
+		if reviewLog.ID == 0 || reviewLogs[reviewLog.ID] {
+			return fmt.Errorf("review log: missing or duplicate id %d", reviewLog.ID)
+		}
+		if !userWords[reviewLog.UserWordID] {
+			return fmt.Errorf("review log %d: unknown user word %d", reviewLog.ID, reviewLog.UserWordID)
+		}
+		if !reviewLog.Grade.Valid() {
+			return fmt.Errorf("review log %d: invalid grade", reviewLog.ID)
+		}
+		reviewLogs[reviewLog.ID] = true
+	}
+	return nil
+}
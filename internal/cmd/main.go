@@ -3,9 +3,16 @@ package main
 import (
 	"learning-cards/internal/startup"
 	"log"
+	"os"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		if err := startup.RunCommand(os.Args[1], os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 	if err := startup.Run(); err != nil {
 		log.Fatal(err)
 	}
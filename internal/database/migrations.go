@@ -1,7 +1,11 @@
 package database
 
 import (
+	"errors"
+	"learning-cards/config"
 	"learning-cards/internal/models"
+	"learning-cards/internal/repository"
+	"learning-cards/internal/utils"
 	"log"
 
 	"gorm.io/gorm"
@@ -9,10 +13,140 @@ import (
 
 func Migrate(db *gorm.DB) {
 	modelsList := []interface{}{
+		&models.User{},
+		&models.Session{},
+		&models.APIKey{},
+		&models.Deck{},
 		&models.Word{},
+		&models.WordTranslation{},
+		&models.Media{},
 		&models.UserWord{},
+		&models.ReviewLog{},
+	}
+	if err := clearMissingDecks(db); err != nil {
+		log.Println("clearing missing decks failed:", err)
 	}
 	if err := db.AutoMigrate(modelsList...); err != nil {
 		log.Println("migration failed:", err)
 	}
+	if err := migrateToUsers(db); err != nil {
+		log.Println("migration to users failed:", err)
+	}
+	if err := migrateCategoriesToDecks(db); err != nil {
+		log.Println("migration of categories to decks failed:", err)
+	}
+	if err := migrateGenders(db); err != nil {
+		log.Println("migration of genders failed:", err)
+	}
+	if err := migrateTranslations(db); err != nil {
+		log.Println("migration of translations failed:", err)
+	}
+}
+
+// clearMissingDecks unlinks the words from decks that no longer exist, which
+// was possible before words.deck_id had a foreign key. Run before AutoMigrate
+// adds the constraint. The words are put back into a deck by their category.
+func clearMissingDecks(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&models.Word{}) || !db.Migrator().HasTable(&models.Deck{}) {
+		return nil
+	}
+	return db.Model(&models.Word{}).
+		Where("deck_id IS NOT NULL AND deck_id NOT IN (?)", db.Model(&models.Deck{}).Select("id")).
+		Update("deck_id", nil).Error
+}
+
+// migrateToUsers moves the progress recorded before accounts existed to the
+// default user. user_words used to be unique per word, then per user and word
+// and are now unique per user and card (word, card type and direction).
+func migrateToUsers(db *gorm.DB) error {
+	if db.Migrator().HasConstraint(&models.UserWord{}, "uni_user_words_word_id") {
+		if err := db.Migrator().DropConstraint(&models.UserWord{}, "uni_user_words_word_id"); err != nil {
+			return err
+		}
+	}
+	for _, index := range []string{"idx_user_words_user_word", "idx_user_words_card"} {
+		if db.Migrator().HasIndex(&models.UserWord{}, index) {
+			if err := db.Migrator().DropIndex(&models.UserWord{}, index); err != nil {
+				return err
+			}
+		}
+	}
+
+	var defaultUser models.User
+	err := db.Where("username = ?", models.DefaultUsername).First(&defaultUser).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		defaultUser = models.User{Username: models.DefaultUsername}
+		err = db.Create(&defaultUser).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	return db.Model(&models.UserWord{}).
+		Where("user_id = 0 OR user_id IS NULL").
+		Update("user_id", defaultUser.ID).Error
+}
+
+// migrateCategoriesToDecks creates a deck for every distinct category of the
+// words without deck and links the words to it.
+func migrateCategoriesToDecks(db *gorm.DB) error {
+	// Decks created before nesting existed are top level decks
+	if err := db.Model(&models.Deck{}).Where("path = ''").Update("path", gorm.Expr("name")).Error; err != nil {
+		return err
+	}
+
+	var categories []string
+	if err := db.Model(&models.Word{}).
+		Where("deck_id IS NULL AND category <> ''").
+		Distinct().
+		Pluck("category", &categories).Error; err != nil {
+		return err
+	}
+
+	decks := repository.NewDeckRepository(db, config.LoadDeckConfig())
+	for _, category := range categories {
+		deck, err := decks.EnsureDeck(category)
+		if errors.Is(err, repository.ErrEmptyDeckName) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := db.Model(&models.Word{}).
+			Where("deck_id IS NULL AND category = ?", category).
+			Updates(map[string]interface{}{"deck_id": deck.ID, "category": deck.Path}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateGenders parses the gender of the words stored before genders existed
+// from their leading article.
+func migrateGenders(db *gorm.DB) error {
+	var words []models.Word
+	if err := db.Where("gender = '' AND translation_gender = ''").Find(&words).Error; err != nil {
+		return err
+	}
+	for _, word := range words {
+		gender, translationGender := utils.ParseGender(word.Word), utils.ParseGender(word.Translation)
+		if gender == "" && translationGender == "" {
+			continue
+		}
+		if err := db.Model(&word).Updates(map[string]interface{}{
+			"gender":             gender,
+			"translation_gender": translationGender,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateTranslations adds the translation of the words stored before
+// alternative translations existed as their primary translation.
+func migrateTranslations(db *gorm.DB) error {
+	return db.Exec(`INSERT INTO word_translations (word_id, translation, is_primary)
+		SELECT words.id, words.translation, ? FROM words
+		WHERE NOT EXISTS (SELECT 1 FROM word_translations WHERE word_translations.word_id = words.id)`, true).Error
 }
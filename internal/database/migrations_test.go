@@ -46,6 +46,9 @@ func TestMigrateCreatesTablesAndColumns(t *testing.T) {
 	if !db.Migrator().HasTable(&models.UserWord{}) {
 		t.Fatalf("expected table for models.UserWord to exist after migration")
 	}
+	if !db.Migrator().HasTable(&models.ReviewLog{}) {
+		t.Fatalf("expected table for models.ReviewLog to exist after migration")
+	}
 
 	// Verify some expected columns exist on the Word model.
 	// Use struct field names as GORM checks them.
@@ -56,3 +59,157 @@ func TestMigrateCreatesTablesAndColumns(t *testing.T) {
 		}
 	}
 }
+
+func TestMigrateAssignsExistingProgressToDefaultUser(t *testing.T) {
+	db := openInMemoryDB(t)
+	dbpkg.Migrate(db)
+
+	// Progress recorded before accounts existed has no user
+	word := models.Word{Word: "el perro", Translation: "der Hund", Category: "animals"}
+	if err := db.Create(&word).Error; err != nil {
+		t.Fatalf("failed to seed word: %v", err)
+	}
+	if err := db.Exec("INSERT INTO user_words (word_id, user_id) VALUES (?, 0)", word.ID).Error; err != nil {
+		t.Fatalf("failed to seed legacy user word: %v", err)
+	}
+
+	dbpkg.Migrate(db)
+
+	var defaultUser models.User
+	if err := db.Where("username = ?", models.DefaultUsername).First(&defaultUser).Error; err != nil {
+		t.Fatalf("expected default user after migration: %v", err)
+	}
+	var userWord models.UserWord
+	if err := db.Where("word_id = ?", word.ID).First(&userWord).Error; err != nil {
+		t.Fatalf("failed to fetch user word: %v", err)
+	}
+	if userWord.UserID != defaultUser.ID {
+		t.Fatalf("expected user word to belong to the default user %d, got %d", defaultUser.ID, userWord.UserID)
+	}
+}
+
+func TestMigrateMovesCategoriesToDecks(t *testing.T) {
+	db := openInMemoryDB(t)
+	dbpkg.Migrate(db)
+
+	words := []models.Word{
+		{Word: "el perro", Translation: "der Hund", Category: "animals"},
+		{Word: "la gata", Translation: "die Katze", Category: "Animals "},
+		{Word: "rojo", Translation: "rot", Category: "colors"},
+	}
+	if err := db.Create(&words).Error; err != nil {
+		t.Fatalf("failed to seed words: %v", err)
+	}
+
+	dbpkg.Migrate(db)
+
+	var decks []models.Deck
+	if err := db.Order("slug").Find(&decks).Error; err != nil {
+		t.Fatalf("failed to fetch decks: %v", err)
+	}
+	if len(decks) != 2 || decks[0].Slug != "animals" || decks[1].Slug != "colors" {
+		t.Fatalf("expected decks animals and colors, got %+v", decks)
+	}
+
+	var migrated []models.Word
+	if err := db.Order("id").Find(&migrated).Error; err != nil {
+		t.Fatalf("failed to fetch words: %v", err)
+	}
+	for i, word := range migrated[:2] {
+		if word.DeckID == nil || *word.DeckID != decks[0].ID || word.Category != decks[0].Name {
+			t.Fatalf("expected word %d in deck %d, got deck %v category %q", i, decks[0].ID, word.DeckID, word.Category)
+		}
+	}
+}
+
+func TestMigrateParsesGenders(t *testing.T) {
+	db := openInMemoryDB(t)
+	dbpkg.Migrate(db)
+
+	words := []models.Word{
+		{Word: "el perro", Translation: "der Hund"},
+		{Word: "la casa", Translation: "das Haus"},
+		{Word: "rojo", Translation: "rot"},
+	}
+	if err := db.Create(&words).Error; err != nil {
+		t.Fatalf("failed to seed words: %v", err)
+	}
+
+	dbpkg.Migrate(db)
+
+	var migrated []models.Word
+	if err := db.Order("id").Find(&migrated).Error; err != nil {
+		t.Fatalf("failed to fetch words: %v", err)
+	}
+	want := [][2]models.Gender{
+		{models.GenderMasculine, models.GenderMasculine},
+		{models.GenderFeminine, models.GenderNeuter},
+		{"", ""},
+	}
+	for i, word := range migrated {
+		if word.Gender != want[i][0] || word.TranslationGender != want[i][1] {
+			t.Fatalf("unexpected genders for %q: %q, %q", word.Word, word.Gender, word.TranslationGender)
+		}
+	}
+}
+
+func TestMigrateAddsPrimaryTranslations(t *testing.T) {
+	db := openInMemoryDB(t)
+	dbpkg.Migrate(db)
+
+	word := models.Word{Word: "el perro", Translation: "der Hund"}
+	if err := db.Create(&word).Error; err != nil {
+		t.Fatalf("failed to seed word: %v", err)
+	}
+
+	dbpkg.Migrate(db)
+	dbpkg.Migrate(db)
+
+	var translations []models.WordTranslation
+	if err := db.Where("word_id = ?", word.ID).Find(&translations).Error; err != nil {
+		t.Fatalf("failed to fetch translations: %v", err)
+	}
+	if len(translations) != 1 || translations[0].Translation != "der Hund" || !translations[0].IsPrimary {
+		t.Fatalf("expected one primary translation, got %+v", translations)
+	}
+}
+
+func TestMigrateDeckForeignKey(t *testing.T) {
+	db := openInMemoryDB(t)
+	dbpkg.Migrate(db)
+
+	// Without the foreign key enforced a word can point at a missing deck, as before the key existed
+	if err := db.Exec("INSERT INTO words (word, translation, category, deck_id) VALUES ('el perro', 'der Hund', 'animals', 999)").Error; err != nil {
+		t.Fatalf("failed to seed word: %v", err)
+	}
+	dbpkg.Migrate(db)
+
+	var word models.Word
+	if err := db.Where("word = ?", "el perro").First(&word).Error; err != nil {
+		t.Fatalf("failed to fetch word: %v", err)
+	}
+	var deck models.Deck
+	if err := db.Where("slug = ?", "animals").First(&deck).Error; err != nil {
+		t.Fatalf("expected the word to get a deck from its category: %v", err)
+	}
+	if word.DeckID == nil || *word.DeckID != deck.ID {
+		t.Fatalf("expected word in deck %d, got %v", deck.ID, word.DeckID)
+	}
+
+	// Deleting the deck unlinks its words
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.Exec("PRAGMA foreign_keys = ON").Error; err != nil {
+		t.Fatalf("failed to enable foreign keys: %v", err)
+	}
+	if err := db.Delete(&deck).Error; err != nil {
+		t.Fatalf("failed to delete deck: %v", err)
+	}
+	var dangling int64
+	if err := db.Model(&models.Word{}).Where("deck_id IS NOT NULL").Count(&dangling).Error; err != nil {
+		t.Fatalf("failed to count words: %v", err)
+	}
+	if dangling != 0 {
+		t.Fatalf("expected no word to keep the deleted deck, got %d", dangling)
+	}
+}
@@ -0,0 +1,251 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"learning-cards/internal/answer"
+	"learning-cards/internal/models"
+)
+
+func TestAnswerWord(t *testing.T) {
+	router, db := setupAuthTest(t)
+	token := registerAdmin(t, router, db, "ana")
+
+	w := doJSON(t, router, http.MethodPost, "/v1/words", token,
+		map[string]string{"word": "la reunión", "translation": "die Besprechung", "category": "business"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 on create, got %d, body: %s", w.Code, w.Body.String())
+	}
+	var word models.Word
+	if err := json.Unmarshal(w.Body.Bytes(), &word); err != nil {
+		t.Fatalf("failed to unmarshal word: %v", err)
+	}
+	path := "/v1/words/" + strconv.FormatUint(uint64(word.ID), 10) + "/answer"
+
+	type response struct {
+		Verdict answer.Verdict   `json:"verdict"`
+		Grade   models.Grade     `json:"grade"`
+		Diff    []answer.Segment `json:"diff"`
+	}
+	check := func(body map[string]string, wantVerdict answer.Verdict, wantBox uint) response {
+		t.Helper()
+		w := doJSON(t, router, http.MethodPost, path, token, body)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200 on answer, got %d, body: %s", w.Code, w.Body.String())
+		}
+		var got response
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatalf("failed to unmarshal answer: %v", err)
+		}
+		if got.Verdict != wantVerdict || got.Grade != wantVerdict.Grade() {
+			t.Fatalf("expected %s, got %+v", wantVerdict, got)
+		}
+		var userWord models.UserWord
+		if err := db.Where("word_id = ?", word.ID).First(&userWord).Error; err != nil {
+			t.Fatalf("failed to fetch user word: %v", err)
+		}
+		if userWord.BoxNumber != wantBox {
+			t.Fatalf("expected box %d after %s answer, got %d", wantBox, wantVerdict, userWord.BoxNumber)
+		}
+		return got
+	}
+
+	check(map[string]string{"answer": "besprechung"}, answer.VerdictCorrect, 2)
+	got := check(map[string]string{"answer": "die Besprechnug"}, answer.VerdictAlmost, 2)
+	if len(got.Diff) < 2 || got.Diff[0].Op != answer.OpEqual {
+		t.Fatalf("expected a diff for the typo, got %+v", got.Diff)
+	}
+	check(map[string]string{"answer": "das Protokoll"}, answer.VerdictWrong, 1)
+
+	var reviews int64
+	db.Model(&models.ReviewLog{}).Count(&reviews)
+	if reviews != 3 {
+		t.Fatalf("expected 3 logged reviews, got %d", reviews)
+	}
+
+	if w := doJSON(t, router, http.MethodPost, path, token, map[string]string{"answer": "la reunion", "direction": "reverse"}); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a missing reverse card, got %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodPost, "/v1/words/999/answer", token, map[string]string{"answer": "x"}); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown word, got %d", w.Code)
+	}
+
+	// Long answers are rejected before they are checked
+	if w := doJSON(t, router, http.MethodPost, path, token, map[string]string{"answer": strings.Repeat("ü", answer.MaxLength+1)}); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a long answer, got %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodPost, path, token, map[string]string{"answer": strings.Repeat("x", 1<<20)}); w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413 for a large body, got %d", w.Code)
+	}
+	db.Model(&models.ReviewLog{}).Count(&reviews)
+	if reviews != 3 {
+		t.Fatalf("expected rejected answers not to be logged, got %d reviews", reviews)
+	}
+}
+
+func TestAnswerRetiredWord(t *testing.T) {
+	router, db := setupAuthTest(t)
+	token := registerAdmin(t, router, db, "ana")
+
+	w := doJSON(t, router, http.MethodPost, "/v1/words", token,
+		map[string]string{"word": "el perro", "translation": "der Hund", "category": "animals"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 on create, got %d, body: %s", w.Code, w.Body.String())
+	}
+	var word models.Word
+	if err := json.Unmarshal(w.Body.Bytes(), &word); err != nil {
+		t.Fatalf("failed to unmarshal word: %v", err)
+	}
+	// Removed from its data file, the card keeps its state but is no longer studied
+	if err := db.Model(&models.Word{}).Where("id = ?", word.ID).Update("retired_at", time.Now()).Error; err != nil {
+		t.Fatalf("failed to retire word: %v", err)
+	}
+
+	id := strconv.FormatUint(uint64(word.ID), 10)
+	if w := doJSON(t, router, http.MethodPost, "/v1/words/"+id+"/answer", token, map[string]string{"answer": "der Hund"}); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 answering a retired word, got %d, body: %s", w.Code, w.Body.String())
+	}
+	if w := doJSON(t, router, http.MethodPut, "/v1/words/update/"+id, token, map[string]string{"grade": "good"}); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 grading a retired word, got %d, body: %s", w.Code, w.Body.String())
+	}
+	var reviews int64
+	db.Model(&models.ReviewLog{}).Count(&reviews)
+	if reviews != 0 {
+		t.Fatalf("expected no logged reviews, got %d", reviews)
+	}
+}
+
+func TestGenderCards(t *testing.T) {
+	router, db := setupAuthTest(t)
+	token := registerAdmin(t, router, db, "ana")
+
+	var words []models.Word
+	for _, body := range []map[string]string{
+		{"word": "el perro", "translation": "der Hund", "category": "animals"},
+		{"word": "rojo", "translation": "rot", "category": "animals"},
+	} {
+		w := doJSON(t, router, http.MethodPost, "/v1/words", token, body)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("expected 201 on create, got %d, body: %s", w.Code, w.Body.String())
+		}
+		var word models.Word
+		if err := json.Unmarshal(w.Body.Bytes(), &word); err != nil {
+			t.Fatalf("failed to unmarshal word: %v", err)
+		}
+		words = append(words, word)
+	}
+	perro := words[0]
+	if perro.Gender != models.GenderMasculine || perro.TranslationGender != models.GenderMasculine || words[1].Gender != "" {
+		t.Fatalf("expected genders parsed from the articles, got %+v", words)
+	}
+
+	deckPath := "/v1/decks/" + strconv.FormatUint(uint64(*perro.DeckID), 10)
+	if w := doJSON(t, router, http.MethodPatch, deckPath, token, map[string]bool{"gender_cards": true}); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on deck update, got %d, body: %s", w.Code, w.Body.String())
+	}
+
+	w := doJSON(t, router, http.MethodGet, "/v1/words/daily", token, nil)
+	var userWords []models.UserWord
+	if err := json.Unmarshal(w.Body.Bytes(), &userWords); err != nil {
+		t.Fatalf("failed to unmarshal daily words: %v", err)
+	}
+	var genderCards []models.UserWord
+	for _, userWord := range userWords {
+		if userWord.CardType == models.CardTypeGender {
+			genderCards = append(genderCards, userWord)
+		}
+	}
+	if len(userWords) != 3 || len(genderCards) != 1 || genderCards[0].WordID != perro.ID || genderCards[0].Direction != models.DirectionForward {
+		t.Fatalf("expected two translation cards and a gender card for the noun, got %+v", userWords)
+	}
+
+	path := "/v1/words/" + strconv.FormatUint(uint64(perro.ID), 10) + "/answer"
+	w = doJSON(t, router, http.MethodPost, path, token, map[string]string{"answer": "der", "card_type": "gender"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on answer, got %d, body: %s", w.Code, w.Body.String())
+	}
+	var result struct {
+		Verdict  answer.Verdict `json:"verdict"`
+		Expected string         `json:"expected"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("failed to unmarshal answer: %v", err)
+	}
+	if result.Verdict != answer.VerdictCorrect || result.Expected != "der" {
+		t.Fatalf("expected a correct gender answer, got %+v", result)
+	}
+
+	// The gender card is scheduled separately from the translation card
+	var cards []models.UserWord
+	if err := db.Where("word_id = ?", perro.ID).Order("card_type").Find(&cards).Error; err != nil {
+		t.Fatalf("failed to fetch cards: %v", err)
+	}
+	if len(cards) != 2 || cards[0].CardType != models.CardTypeGender || cards[0].BoxNumber != 2 || cards[1].BoxNumber != 1 {
+		t.Fatalf("expected only the gender card to move up, got %+v", cards)
+	}
+}
+
+func TestClozeCards(t *testing.T) {
+	router, db := setupAuthTest(t)
+	token := registerAdmin(t, router, db, "ana")
+
+	var words []models.Word
+	for _, body := range []map[string]string{
+		{"word": "correr", "translation": "laufen", "category": "verbs", "example_sentence": "Corro cada mañana."},
+		{"word": "saltar", "translation": "springen", "category": "verbs", "example_sentence": "Me gusta el deporte."},
+	} {
+		w := doJSON(t, router, http.MethodPost, "/v1/words", token, body)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("expected 201 on create, got %d, body: %s", w.Code, w.Body.String())
+		}
+		var word models.Word
+		if err := json.Unmarshal(w.Body.Bytes(), &word); err != nil {
+			t.Fatalf("failed to unmarshal word: %v", err)
+		}
+		words = append(words, word)
+	}
+
+	deckPath := "/v1/decks/" + strconv.FormatUint(uint64(*words[0].DeckID), 10)
+	if w := doJSON(t, router, http.MethodPatch, deckPath, token, map[string]bool{"cloze_cards": true}); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on deck update, got %d, body: %s", w.Code, w.Body.String())
+	}
+
+	// The cloze card of a word missing from its sentence is left out
+	w := doJSON(t, router, http.MethodGet, "/v1/words/daily", token, nil)
+	var userWords []models.UserWord
+	if err := json.Unmarshal(w.Body.Bytes(), &userWords); err != nil {
+		t.Fatalf("failed to unmarshal daily words: %v", err)
+	}
+	var clozeCards []models.UserWord
+	for _, userWord := range userWords {
+		if userWord.CardType == models.CardTypeCloze {
+			clozeCards = append(clozeCards, userWord)
+		}
+	}
+	if len(userWords) != 3 || len(clozeCards) != 1 || clozeCards[0].WordID != words[0].ID || clozeCards[0].Cloze != "_____ cada mañana." {
+		t.Fatalf("expected two translation cards and one cloze card, got %+v", userWords)
+	}
+
+	path := "/v1/words/" + strconv.FormatUint(uint64(words[0].ID), 10) + "/answer"
+	w = doJSON(t, router, http.MethodPost, path, token, map[string]string{"answer": "corro", "card_type": "cloze"})
+	var result struct {
+		Verdict  answer.Verdict `json:"verdict"`
+		Expected string         `json:"expected"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("failed to unmarshal answer: %v", err)
+	}
+	if result.Verdict != answer.VerdictCorrect || result.Expected != "Corro" {
+		t.Fatalf("expected the hidden word to be accepted, got %+v", result)
+	}
+
+	path = "/v1/words/" + strconv.FormatUint(uint64(words[1].ID), 10) + "/answer"
+	if w := doJSON(t, router, http.MethodPost, path, token, map[string]string{"answer": "salto", "card_type": "cloze"}); w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 for a word missing from its sentence, got %d", w.Code)
+	}
+}
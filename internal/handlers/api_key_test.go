@@ -0,0 +1,75 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"learning-cards/internal/models"
+)
+
+func TestAPIKeyScopes(t *testing.T) {
+	router, db := setupAuthTest(t)
+
+	word := models.Word{Word: "la gata", Translation: "die Katze", Category: "animals"}
+	if err := db.Create(&word).Error; err != nil {
+		t.Fatalf("failed to seed word: %v", err)
+	}
+	token := registerAndLogin(t, router, "ana")
+
+	newKey := func(scopes []string) (models.APIKey, string) {
+		w := doJSON(t, router, http.MethodPost, "/v1/api-keys", token, map[string]any{"name": "script", "scopes": scopes})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("expected 201 on key creation, got %d, body: %s", w.Code, w.Body.String())
+		}
+		var created struct {
+			Key    models.APIKey `json:"key"`
+			Secret string        `json:"secret"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+			t.Fatalf("failed to unmarshal key: %v", err)
+		}
+		return created.Key, created.Secret
+	}
+	readKey, readSecret := newKey([]string{"read"})
+	_, reviewSecret := newKey([]string{"review"})
+	updatePath := "/v1/words/update/" + strconv.FormatUint(uint64(word.ID), 10)
+
+	if w := doJSON(t, router, http.MethodGet, "/v1/words/daily", readSecret, nil); w.Code != http.StatusOK {
+		t.Fatalf("expected read key to read, got %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodPut, updatePath, readSecret, map[string]string{"grade": "good"}); w.Code != http.StatusForbidden {
+		t.Fatalf("expected read key to be refused a review, got %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodPut, updatePath, reviewSecret, map[string]string{"grade": "good"}); w.Code != http.StatusOK {
+		t.Fatalf("expected review key to review, got %d, body: %s", w.Code, w.Body.String())
+	}
+	if w := doJSON(t, router, http.MethodPost, "/v1/api-keys", reviewSecret, map[string]any{"name": "escalate"}); w.Code != http.StatusForbidden {
+		t.Fatalf("expected review key to be refused key creation, got %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodPost, "/v1/api-keys", token, map[string]any{"name": "bad", "scopes": []string{"admin"}}); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown scope, got %d", w.Code)
+	}
+
+	// Keys are listed with their last use, never with their hash
+	w := doJSON(t, router, http.MethodGet, "/v1/api-keys", token, nil)
+	var keys []map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &keys); err != nil {
+		t.Fatalf("failed to unmarshal keys: %v", err)
+	}
+	if len(keys) != 2 || keys[0]["LastUsedAt"] == nil || keys[0]["KeyHash"] != nil {
+		t.Fatalf("unexpected key listing: %v", keys)
+	}
+
+	revokePath := "/v1/api-keys/" + strconv.FormatUint(uint64(readKey.ID), 10)
+	if w := doJSON(t, router, http.MethodDelete, revokePath, token, nil); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on revoke, got %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodGet, "/v1/words/daily", readSecret, nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected revoked key to be rejected, got %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodDelete, revokePath, token, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 when revoking twice, got %d", w.Code)
+	}
+}
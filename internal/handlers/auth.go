@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"errors"
+	"learning-cards/config"
+	"learning-cards/internal/middleware"
+	"learning-cards/internal/services"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuthHandler struct {
+	service         *services.AuthService
+	userWordService *services.UserWordService
+	cfg             config.AuthConfig
+}
+
+func NewAuthHandler(service *services.AuthService, userWordService *services.UserWordService, cfg config.AuthConfig) *AuthHandler {
+	return &AuthHandler{
+		service:         service,
+		userWordService: userWordService,
+		cfg:             cfg,
+	}
+}
+
+type credentials struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+func (h *AuthHandler) Register(c *gin.Context) {
+	var requestBody credentials
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.service.Register(requestBody.Username, requestBody.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidUsername), errors.Is(err, services.ErrInvalidPassword):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrUsernameTaken):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrRegistrationClosed):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register user"})
+		}
+		return
+	}
+
+	// Give the new learner their cards right away instead of waiting for the cron job
+	if err := h.userWordService.SyncUser(user.ID); err != nil {
+		log.Printf("Error syncing user words for %s: %v", user.Username, err)
+	}
+	c.JSON(http.StatusCreated, user)
+}
+
+func (h *AuthHandler) Login(c *gin.Context) {
+	var requestBody credentials
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := h.service.Login(requestBody.Username, requestBody.Password)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
+		return
+	}
+	h.setSessionCookie(c, tokens.AccessToken, tokens.ExpiresAt)
+	c.JSON(http.StatusOK, tokens)
+}
+
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var requestBody struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := h.service.Refresh(requestBody.RefreshToken)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session"})
+		return
+	}
+	h.setSessionCookie(c, tokens.AccessToken, tokens.ExpiresAt)
+	c.JSON(http.StatusOK, tokens)
+}
+
+func (h *AuthHandler) Logout(c *gin.Context) {
+	if err := h.service.Logout(middleware.AccessToken(c)); err != nil && !errors.Is(err, services.ErrInvalidToken) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+	h.setSessionCookie(c, "", time.Unix(0, 0))
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+func (h *AuthHandler) setSessionCookie(c *gin.Context, token string, expiresAt time.Time) {
+	maxAge := int(time.Until(expiresAt).Seconds())
+	if token == "" {
+		maxAge = -1
+	}
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(middleware.SessionCookie, token, maxAge, "/", "", h.cfg.SecureCookies, true)
+}
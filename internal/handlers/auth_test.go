@@ -0,0 +1,264 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	v1 "learning-cards/api/v1"
+	"learning-cards/config"
+	"learning-cards/internal/handlers"
+	"learning-cards/internal/media"
+	"learning-cards/internal/models"
+	"learning-cards/internal/repository"
+	"learning-cards/internal/scheduler"
+	"learning-cards/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupAuthTest wires the complete v1 API on an in-memory sqlite DB.
+func setupAuthTest(t *testing.T) (*gin.Engine, *gorm.DB) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite DB: %v", err)
+	}
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		_ = sqlDB.Close()
+	})
+	if err := db.AutoMigrate(&models.User{}, &models.Session{}, &models.APIKey{}, &models.Deck{}, &models.Word{}, &models.WordTranslation{}, &models.Media{}, &models.UserWord{}, &models.ReviewLog{}); err != nil {
+		t.Fatalf("auto migrate failed: %v", err)
+	}
+
+	authConfig := config.AuthConfig{AccessTokenTTL: time.Hour, RefreshTokenTTL: 24 * time.Hour, AllowRegistration: true}
+	userRepo := repository.NewUserRepository(db)
+	authService := services.NewAuthService(userRepo, repository.NewSessionRepository(db), authConfig)
+	userWordRepo := repository.NewUserWordRepository(db, scheduler.Leitner{})
+	userWordService := services.NewUserWordService(userWordRepo)
+
+	apiKeyService := services.NewAPIKeyService(repository.NewAPIKeyRepository(db))
+	deckRepo := repository.NewDeckRepository(db, config.DeckConfig{SourceLanguage: "es", TargetLanguage: "de"})
+	wordRepo := repository.NewWordRepository(db)
+	mediaConfig := config.MediaConfig{Directory: t.TempDir(), MaxSize: 1 << 10}
+	mediaStore, err := media.NewLocalStore(mediaConfig.Directory)
+	if err != nil {
+		t.Fatalf("failed to create media store: %v", err)
+	}
+	wordService := services.NewWordService(wordRepo, deckRepo, mediaStore)
+
+	router := gin.New()
+	userService := services.NewUserService(userRepo)
+	v1.RegisterRoutes(router, authService, apiKeyService, userService,
+		handlers.NewAuthHandler(authService, userWordService, authConfig),
+		handlers.NewAPIKeyHandler(apiKeyService),
+		handlers.NewUserWordHandler(userWordService, userService),
+		handlers.NewWordHandler(wordService),
+		handlers.NewDeckHandler(services.NewDeckService(deckRepo)),
+		handlers.NewMediaHandler(services.NewMediaService(repository.NewMediaRepository(db), wordRepo, mediaStore, mediaConfig)),
+		handlers.NewImportHandler(services.NewImportService(wordService, userWordRepo), config.ImportConfig{MaxCollectionSize: 1 << 20}),
+		handlers.NewExportHandler(services.NewExportService(wordRepo, userWordRepo)),
+		handlers.NewBackupHandler(services.NewBackupService(repository.NewBackupRepository(db))),
+	)
+	return router, db
+}
+
+func doJSON(t *testing.T, router *gin.Engine, method, path, token string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader *bytes.Reader
+	if body != nil {
+		bs, _ := json.Marshal(body)
+		reader = bytes.NewReader(bs)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthFlow(t *testing.T) {
+	router, db := setupAuthTest(t)
+
+	if err := db.Create(&models.Word{Word: "el perro", Translation: "der Hund", Category: "animals"}).Error; err != nil {
+		t.Fatalf("failed to seed word: %v", err)
+	}
+
+	// Routes are closed without a token
+	if w := doJSON(t, router, http.MethodGet, "/v1/words/daily", "", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", w.Code)
+	}
+
+	creds := map[string]string{"username": "ana", "password": "correct horse"}
+	if w := doJSON(t, router, http.MethodPost, "/v1/auth/register", "", creds); w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 on register, got %d, body: %s", w.Code, w.Body.String())
+	}
+	// Like a concurrent registration, the second one runs into the unique username
+	if w := doJSON(t, router, http.MethodPost, "/v1/auth/register", "", creds); w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 on duplicate register, got %d", w.Code)
+	}
+
+	wrong := map[string]string{"username": "ana", "password": "wrong password"}
+	if w := doJSON(t, router, http.MethodPost, "/v1/auth/login", "", wrong); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 on wrong password, got %d", w.Code)
+	}
+
+	w := doJSON(t, router, http.MethodPost, "/v1/auth/login", "", creds)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on login, got %d, body: %s", w.Code, w.Body.String())
+	}
+	var tokens services.Tokens
+	if err := json.Unmarshal(w.Body.Bytes(), &tokens); err != nil {
+		t.Fatalf("failed to unmarshal tokens: %v", err)
+	}
+
+	// Registration synced the word for the new user
+	w = doJSON(t, router, http.MethodGet, "/v1/words/daily", tokens.AccessToken, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 with token, got %d, body: %s", w.Code, w.Body.String())
+	}
+	var due []models.UserWord
+	if err := json.Unmarshal(w.Body.Bytes(), &due); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(due) != 1 {
+		t.Fatalf("expected 1 due word for the new user, got %d", len(due))
+	}
+
+	// Refreshing rotates the tokens
+	w = doJSON(t, router, http.MethodPost, "/v1/auth/refresh", "", map[string]string{"refresh_token": tokens.RefreshToken})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on refresh, got %d, body: %s", w.Code, w.Body.String())
+	}
+	var refreshed services.Tokens
+	if err := json.Unmarshal(w.Body.Bytes(), &refreshed); err != nil {
+		t.Fatalf("failed to unmarshal tokens: %v", err)
+	}
+	if w := doJSON(t, router, http.MethodGet, "/v1/words/daily", tokens.AccessToken, nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected old access token to be rejected after refresh, got %d", w.Code)
+	}
+
+	if w := doJSON(t, router, http.MethodPost, "/v1/auth/logout", refreshed.AccessToken, nil); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on logout, got %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodGet, "/v1/words/daily", refreshed.AccessToken, nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 after logout, got %d", w.Code)
+	}
+}
+
+func TestAuthExpiredToken(t *testing.T) {
+	router, db := setupAuthTest(t)
+
+	creds := map[string]string{"username": "ben", "password": "long enough"}
+	doJSON(t, router, http.MethodPost, "/v1/auth/register", "", creds)
+	w := doJSON(t, router, http.MethodPost, "/v1/auth/login", "", creds)
+	var tokens services.Tokens
+	if err := json.Unmarshal(w.Body.Bytes(), &tokens); err != nil {
+		t.Fatalf("failed to unmarshal tokens: %v", err)
+	}
+
+	if err := db.Model(&models.Session{}).Where("1 = 1").Update("expires_at", time.Now().Add(-time.Minute)).Error; err != nil {
+		t.Fatalf("failed to expire session: %v", err)
+	}
+	if w := doJSON(t, router, http.MethodGet, "/v1/words/daily", tokens.AccessToken, nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 with expired token, got %d", w.Code)
+	}
+}
+
+func TestAuthRegisterExistingUserWithoutPassword(t *testing.T) {
+	router, db := setupAuthTest(t)
+
+	// The default user owns the progress recorded before accounts existed
+	if err := db.Create(&models.User{Username: models.DefaultUsername}).Error; err != nil {
+		t.Fatalf("failed to create default user: %v", err)
+	}
+	creds := map[string]string{"username": models.DefaultUsername, "password": "taking it over"}
+	if w := doJSON(t, router, http.MethodPost, "/v1/auth/register", "", creds); w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 on registering the default user, got %d, body: %s", w.Code, w.Body.String())
+	}
+	if w := doJSON(t, router, http.MethodPost, "/v1/auth/login", "", creds); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 on login as the default user, got %d", w.Code)
+	}
+
+	// An operator claims it by setting its password
+	authService := services.NewAuthService(repository.NewUserRepository(db), repository.NewSessionRepository(db), config.AuthConfig{AccessTokenTTL: time.Hour, RefreshTokenTTL: time.Hour})
+	if err := authService.SetPassword(models.DefaultUsername, creds["password"]); err != nil {
+		t.Fatalf("failed to set password: %v", err)
+	}
+	if w := doJSON(t, router, http.MethodPost, "/v1/auth/login", "", creds); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on login after setting the password, got %d, body: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAuthSetPasswordEndsSessions(t *testing.T) {
+	router, db := setupAuthTest(t)
+
+	creds := map[string]string{"username": "ana", "password": "old password"}
+	if w := doJSON(t, router, http.MethodPost, "/v1/auth/register", "", creds); w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 on register, got %d, body: %s", w.Code, w.Body.String())
+	}
+	w := doJSON(t, router, http.MethodPost, "/v1/auth/login", "", creds)
+	var tokens services.Tokens
+	if err := json.Unmarshal(w.Body.Bytes(), &tokens); err != nil {
+		t.Fatalf("failed to unmarshal tokens: %v", err)
+	}
+
+	authService := services.NewAuthService(repository.NewUserRepository(db), repository.NewSessionRepository(db), config.AuthConfig{AccessTokenTTL: time.Hour, RefreshTokenTTL: time.Hour})
+	if err := authService.SetPassword("ana", "new password"); err != nil {
+		t.Fatalf("failed to set password: %v", err)
+	}
+
+	// A session opened before the reset, maybe by someone else, no longer works
+	if w := doJSON(t, router, http.MethodGet, "/v1/words/daily", tokens.AccessToken, nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 with a token from before the reset, got %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodPost, "/v1/auth/refresh", "", map[string]string{"refresh_token": tokens.RefreshToken}); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 refreshing a session from before the reset, got %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodPost, "/v1/auth/login", "", creds); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 with the old password, got %d", w.Code)
+	}
+	creds["password"] = "new password"
+	if w := doJSON(t, router, http.MethodPost, "/v1/auth/login", "", creds); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 with the new password, got %d, body: %s", w.Code, w.Body.String())
+	}
+}
+
+// registerAndLogin creates a user and returns an access token for it.
+func registerAndLogin(t *testing.T, router *gin.Engine, username string) string {
+	t.Helper()
+	creds := map[string]string{"username": username, "password": "password for " + username}
+	if w := doJSON(t, router, http.MethodPost, "/v1/auth/register", "", creds); w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 on register, got %d, body: %s", w.Code, w.Body.String())
+	}
+	w := doJSON(t, router, http.MethodPost, "/v1/auth/login", "", creds)
+	var tokens services.Tokens
+	if err := json.Unmarshal(w.Body.Bytes(), &tokens); err != nil {
+		t.Fatalf("failed to unmarshal tokens: %v", err)
+	}
+	return tokens.AccessToken
+}
+
+// registerAdmin creates a user with admin rights, who may change the shared
+// words and decks, and returns an access token for it.
+func registerAdmin(t *testing.T, router *gin.Engine, db *gorm.DB, username string) string {
+	t.Helper()
+	token := registerAndLogin(t, router, username)
+	if err := db.Model(&models.User{}).Where("username = ?", username).Update("is_admin", true).Error; err != nil {
+		t.Fatalf("failed to grant admin rights: %v", err)
+	}
+	return token
+}
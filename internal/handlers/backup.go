@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"errors"
+	"learning-cards/internal/backup"
+	"learning-cards/internal/services"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxRestoreSize limits the size of an uploaded backup
+const maxRestoreSize = 1 << 30
+
+type BackupHandler struct {
+	service *services.BackupService
+}
+
+func NewBackupHandler(service *services.BackupService) *BackupHandler {
+	return &BackupHandler{service: service}
+}
+
+// Backup streams the collection. Once streaming started errors can only be
+// logged, a truncated backup fails to restore for lack of records it refers to.
+func (h *BackupHandler) Backup(c *gin.Context) {
+	filename := "learning-cards-" + time.Now().UTC().Format("20060102-150405") + ".ndjson"
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+	if err := h.service.Backup(c.Writer); err != nil {
+		log.Printf("backup failed: %v", err)
+		c.Abort()
+	}
+}
+
+// Restore replaces the collection with the backup in the request body, or with
+// ?dry_run=true only reports what would change
+func (h *BackupHandler) Restore(c *gin.Context) {
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dry_run flag"})
+		return
+	}
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, maxRestoreSize)
+	changes, err := h.service.Restore(body, dryRun)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Backup too large"})
+		case errors.Is(err, backup.ErrUnsupportedVersion), errors.Is(err, backup.ErrInvalidBackup):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			log.Printf("restore failed: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore backup, nothing was changed"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"dry_run": dryRun, "changes": changes})
+}
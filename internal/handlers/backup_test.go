@@ -0,0 +1,146 @@
+package handlers_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"learning-cards/internal/backup"
+	"learning-cards/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type restoreResponse struct {
+	DryRun  bool           `json:"dry_run"`
+	Changes backup.Changes `json:"changes"`
+	Error   string         `json:"error"`
+}
+
+func restore(t *testing.T, router *gin.Engine, token string, body []byte, dryRun bool) (int, restoreResponse) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/v1/admin/restore?dry_run="+strconv.FormatBool(dryRun), bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var response restoreResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w.Code, response
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	router, db := setupAuthTest(t)
+	token := registerAndLogin(t, router, "ana")
+
+	if w := doJSON(t, router, http.MethodGet, "/v1/admin/backup", token, nil); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a user without admin rights, got %d", w.Code)
+	}
+	db.Model(&models.User{}).Where("username = ?", "ana").Update("is_admin", true)
+
+	for _, body := range []map[string]any{
+		{"word": "el perro", "translation": "der Hund", "alternatives": []string{"der Köter"}, "category": "animals::pets"},
+		{"word": "la gata", "translation": "die Katze", "category": "animals"},
+	} {
+		if w := doJSON(t, router, http.MethodPost, "/v1/words", token, body); w.Code != http.StatusCreated {
+			t.Fatalf("expected 201 on create, got %d, body: %s", w.Code, w.Body.String())
+		}
+	}
+	var perro models.Word
+	db.Where("word = ?", "el perro").First(&perro)
+	perroPath := "/v1/words/" + strconv.FormatUint(uint64(perro.ID), 10)
+	doJSON(t, router, http.MethodPut, "/v1/words/update/"+strconv.FormatUint(uint64(perro.ID), 10), token, map[string]any{"grade": "good"})
+
+	w := doJSON(t, router, http.MethodGet, "/v1/admin/backup", token, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on backup, got %d, body: %s", w.Code, w.Body.String())
+	}
+	saved := w.Body.Bytes()
+	counts := map[string]int{}
+	scanner := bufio.NewScanner(bytes.NewReader(saved))
+	for scanner.Scan() {
+		var line struct{ Type string }
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("backup line is not JSON: %q", scanner.Text())
+		}
+		if len(counts) == 0 && line.Type != backup.TypeHeader {
+			t.Fatalf("expected the header first, got %q", line.Type)
+		}
+		counts[line.Type]++
+	}
+	if counts[backup.TypeUser] != 1 || counts[backup.TypeDeck] != 2 || counts[backup.TypeWord] != 2 ||
+		counts[backup.TypeUserWord] != 2 || counts[backup.TypeReviewLog] != 1 {
+		t.Fatalf("unexpected records in backup: %v", counts)
+	}
+
+	code, response := restore(t, router, token, saved, true)
+	if code != http.StatusOK || response.Changes.Words.Unchanged != 2 || response.Changes.Words.Created+response.Changes.Words.Updated+response.Changes.Words.Deleted != 0 {
+		t.Fatalf("expected no changes restoring an unchanged collection, got %d %+v", code, response)
+	}
+
+	// Change the collection after the backup
+	doJSON(t, router, http.MethodDelete, perroPath, token, nil)
+	doJSON(t, router, http.MethodPost, "/v1/words", token, map[string]string{"word": "rojo", "translation": "rot", "category": "colors"})
+
+	code, response = restore(t, router, token, saved, true)
+	if code != http.StatusOK || !response.DryRun {
+		t.Fatalf("expected 200 on dry run, got %d %+v", code, response)
+	}
+	if words := response.Changes.Words; words.Created != 1 || words.Deleted != 1 || words.Unchanged != 1 {
+		t.Fatalf("unexpected word changes: %+v", words)
+	}
+	if response.Changes.ReviewLogs.Created != 1 || response.Changes.Decks.Deleted != 1 {
+		t.Fatalf("unexpected changes: %+v", response.Changes)
+	}
+	var count int64
+	db.Model(&models.Word{}).Where("word = ?", "rojo").Count(&count)
+	if count != 1 {
+		t.Fatalf("expected the dry run to change nothing")
+	}
+
+	code, response = restore(t, router, token, saved, false)
+	if code != http.StatusOK || response.DryRun {
+		t.Fatalf("expected 200 on restore, got %d %+v", code, response)
+	}
+	db.Model(&models.Word{}).Where("word = ?", "rojo").Count(&count)
+	if count != 0 {
+		t.Fatalf("expected words created after the backup to be gone")
+	}
+	// The session survives, the word is back with its translations and progress
+	w = doJSON(t, router, http.MethodGet, perroPath, token, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the deleted word to be restored, got %d", w.Code)
+	}
+	var restored models.Word
+	json.Unmarshal(w.Body.Bytes(), &restored)
+	if len(restored.Alternatives()) != 1 || restored.Alternatives()[0] != "der Köter" {
+		t.Fatalf("expected the alternative translation to be restored, got %+v", restored.Translations)
+	}
+	w = doJSON(t, router, http.MethodGet, perroPath+"/history", token, nil)
+	var history []models.ReviewLog
+	json.Unmarshal(w.Body.Bytes(), &history)
+	if len(history) != 1 {
+		t.Fatalf("expected the review history to be restored, got %d entries", len(history))
+	}
+	if w := doJSON(t, router, http.MethodPost, "/v1/words", token, map[string]string{"word": "azul", "translation": "blau"}); w.Code != http.StatusCreated {
+		t.Fatalf("expected new words after a restore, got %d, body: %s", w.Code, w.Body.String())
+	}
+
+	newer := strings.Replace(string(saved), `"version":1`, `"version":2`, 1)
+	if code, response := restore(t, router, token, []byte(newer), false); code != http.StatusBadRequest || !strings.Contains(response.Error, "version") {
+		t.Fatalf("expected 400 for an unsupported version, got %d %+v", code, response)
+	}
+	broken := string(saved) + `{"type":"review_log","data":{"id":999,"user_word_id":12345,"grade":"good"}}` + "\n"
+	if code, _ := restore(t, router, token, []byte(broken), false); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a dangling reference, got %d", code)
+	}
+	db.Model(&models.Word{}).Where("word = ?", "azul").Count(&count)
+	if count != 1 {
+		t.Fatalf("expected a rejected backup to change nothing")
+	}
+}
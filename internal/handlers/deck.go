@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"errors"
+	"learning-cards/internal/middleware"
+	"learning-cards/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type DeckHandler struct {
+	service *services.DeckService
+}
+
+func NewDeckHandler(service *services.DeckService) *DeckHandler {
+	return &DeckHandler{service: service}
+}
+
+func (h *DeckHandler) GetDecks(c *gin.Context) {
+	decks, err := h.service.GetDecks(middleware.UserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve decks."})
+		return
+	}
+	c.JSON(http.StatusOK, decks)
+}
+
+func (h *DeckHandler) UpdateDeck(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("deckID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid deck ID"})
+		return
+	}
+	var requestBody struct {
+		Description    *string `json:"description"`
+		SourceLanguage *string `json:"source_language"`
+		TargetLanguage *string `json:"target_language"`
+		ReverseCards   *bool   `json:"reverse_cards"`
+		GenderCards    *bool   `json:"gender_cards"`
+		ClozeCards     *bool   `json:"cloze_cards"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	deck, err := h.service.UpdateDeck(uint(id), services.DeckChanges{
+		Description:    requestBody.Description,
+		SourceLanguage: requestBody.SourceLanguage,
+		TargetLanguage: requestBody.TargetLanguage,
+		ReverseCards:   requestBody.ReverseCards,
+		GenderCards:    requestBody.GenderCards,
+		ClozeCards:     requestBody.ClozeCards,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Deck not found"})
+		case errors.Is(err, services.ErrInvalidDeck):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update deck"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, deck)
+}
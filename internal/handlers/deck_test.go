@@ -0,0 +1,258 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"learning-cards/internal/models"
+	"learning-cards/internal/repository"
+)
+
+func TestGetDecksCountsCards(t *testing.T) {
+	router, db := setupAuthTest(t)
+	token := registerAdmin(t, router, db, "ana")
+
+	for _, body := range []map[string]string{
+		{"word": "el perro", "translation": "der Hund", "category": "animals"},
+		{"word": "la gata", "translation": "die Katze", "category": "Animals"},
+		{"word": "rojo", "translation": "rot", "category": "colors"},
+	} {
+		if w := doJSON(t, router, http.MethodPost, "/v1/words", token, body); w.Code != http.StatusCreated {
+			t.Fatalf("expected 201 on create, got %d, body: %s", w.Code, w.Body.String())
+		}
+	}
+
+	// One animal card was reviewed and is not due before tomorrow
+	var perro models.Word
+	if err := db.Where("word = ?", "el perro").First(&perro).Error; err != nil {
+		t.Fatalf("failed to fetch word: %v", err)
+	}
+	if err := db.Model(&models.UserWord{}).Where("word_id = ?", perro.ID).
+		Updates(map[string]interface{}{"correct_attempts": 1, "next_review": time.Now().Add(24 * time.Hour)}).Error; err != nil {
+		t.Fatalf("failed to update user word: %v", err)
+	}
+
+	w := doJSON(t, router, http.MethodGet, "/v1/decks", token, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body: %s", w.Code, w.Body.String())
+	}
+	var decks []repository.DeckSummary
+	if err := json.Unmarshal(w.Body.Bytes(), &decks); err != nil {
+		t.Fatalf("failed to unmarshal decks: %v", err)
+	}
+	if len(decks) != 2 {
+		t.Fatalf("expected 2 decks, got %+v", decks)
+	}
+	animals := decks[0]
+	if animals.Slug != "animals" || animals.TotalCards != 2 || animals.DueCards != 1 || animals.NewCards != 1 {
+		t.Fatalf("unexpected animals summary: %+v", animals)
+	}
+	if animals.SourceLanguage != "es" || animals.TargetLanguage != "de" {
+		t.Fatalf("expected default languages on the new deck, got %+v", animals.Deck)
+	}
+
+	path := "/v1/decks/" + strconv.FormatUint(uint64(animals.ID), 10)
+	w = doJSON(t, router, http.MethodPatch, path, token, map[string]string{"description": "Tiere"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on deck update, got %d, body: %s", w.Code, w.Body.String())
+	}
+	var updated models.Deck
+	if err := json.Unmarshal(w.Body.Bytes(), &updated); err != nil {
+		t.Fatalf("failed to unmarshal deck: %v", err)
+	}
+	if updated.Description != "Tiere" || updated.Name != animals.Name {
+		t.Fatalf("unexpected updated deck: %+v", updated)
+	}
+}
+
+func TestNestedDecks(t *testing.T) {
+	router, db := setupAuthTest(t)
+	token := registerAdmin(t, router, db, "ana")
+
+	for _, body := range []map[string]string{
+		{"word": "la empresa", "translation": "das Unternehmen", "category": "business"},
+		{"word": "la reunión", "translation": "die Besprechung", "category": "business::meetings"},
+		{"word": "el acta", "translation": "das Protokoll", "category": " Business :: Meetings "},
+	} {
+		if w := doJSON(t, router, http.MethodPost, "/v1/words", token, body); w.Code != http.StatusCreated {
+			t.Fatalf("expected 201 on create, got %d, body: %s", w.Code, w.Body.String())
+		}
+	}
+
+	w := doJSON(t, router, http.MethodGet, "/v1/decks", token, nil)
+	var decks []repository.DeckSummary
+	if err := json.Unmarshal(w.Body.Bytes(), &decks); err != nil {
+		t.Fatalf("failed to unmarshal decks: %v", err)
+	}
+	if len(decks) != 2 {
+		t.Fatalf("expected a parent and a child deck, got %+v", decks)
+	}
+	business, meetings := decks[0], decks[1]
+	if meetings.Slug != "business::meetings" || meetings.Name != "meetings" || meetings.ParentID == nil || *meetings.ParentID != business.ID {
+		t.Fatalf("unexpected child deck: %+v", meetings.Deck)
+	}
+	if business.DueCards != 3 || meetings.DueCards != 2 {
+		t.Fatalf("expected due counts rolled up to the parent, got business=%d meetings=%d", business.DueCards, meetings.DueCards)
+	}
+
+	for path, want := range map[string]int{
+		"/v1/words/category/business":                         1,
+		"/v1/words/category/business?descendants=true":        3,
+		"/v1/words/category/business::meetings":               2,
+		"/v1/words/category/business::meetings?descendants=1": 2,
+	} {
+		w := doJSON(t, router, http.MethodGet, path, token, nil)
+		var userWords []models.UserWord
+		if err := json.Unmarshal(w.Body.Bytes(), &userWords); err != nil {
+			t.Fatalf("failed to unmarshal user words for %s: %v", path, err)
+		}
+		if len(userWords) != want {
+			t.Fatalf("expected %d user words for %s, got %d", want, path, len(userWords))
+		}
+	}
+}
+
+func TestReverseCards(t *testing.T) {
+	router, db := setupAuthTest(t)
+	token := registerAdmin(t, router, db, "ana")
+
+	w := doJSON(t, router, http.MethodPost, "/v1/words", token,
+		map[string]string{"word": "el perro", "translation": "der Hund", "category": "animals"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 on create, got %d, body: %s", w.Code, w.Body.String())
+	}
+	var word models.Word
+	if err := json.Unmarshal(w.Body.Bytes(), &word); err != nil {
+		t.Fatalf("failed to unmarshal word: %v", err)
+	}
+	deckPath := "/v1/decks/" + strconv.FormatUint(uint64(*word.DeckID), 10)
+	wordPath := "/v1/words/update/" + strconv.FormatUint(uint64(word.ID), 10)
+
+	daily := func() map[models.Direction]models.UserWord {
+		t.Helper()
+		w := doJSON(t, router, http.MethodGet, "/v1/words/daily", token, nil)
+		var userWords []models.UserWord
+		if err := json.Unmarshal(w.Body.Bytes(), &userWords); err != nil {
+			t.Fatalf("failed to unmarshal daily words: %v", err)
+		}
+		cards := make(map[models.Direction]models.UserWord)
+		for _, userWord := range userWords {
+			cards[userWord.Direction] = userWord
+		}
+		return cards
+	}
+	if cards := daily(); len(cards) != 1 || cards[models.DirectionForward].WordID != word.ID {
+		t.Fatalf("expected only the forward card, got %+v", cards)
+	}
+	if w := doJSON(t, router, http.MethodPut, wordPath, token, map[string]string{"grade": "good", "direction": "reverse"}); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a missing reverse card, got %d", w.Code)
+	}
+
+	if w := doJSON(t, router, http.MethodPatch, deckPath, token, map[string]bool{"reverse_cards": true}); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on deck update, got %d, body: %s", w.Code, w.Body.String())
+	}
+	if cards := daily(); len(cards) != 2 || cards[models.DirectionReverse].WordID != word.ID {
+		t.Fatalf("expected a forward and a reverse card, got %+v", cards)
+	}
+
+	// Reviewing the reverse card leaves the forward card due
+	if w := doJSON(t, router, http.MethodPut, wordPath, token, map[string]string{"grade": "good", "direction": "reverse"}); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on update, got %d, body: %s", w.Code, w.Body.String())
+	}
+	if cards := daily(); len(cards) != 1 || cards[models.DirectionForward].WordID != word.ID {
+		t.Fatalf("expected only the forward card to be due, got %+v", cards)
+	}
+	historyPath := "/v1/words/" + strconv.FormatUint(uint64(word.ID), 10) + "/history"
+	for direction, want := range map[string]int{"forward": 0, "reverse": 1} {
+		w := doJSON(t, router, http.MethodGet, historyPath+"?direction="+direction, token, nil)
+		var reviewLogs []models.ReviewLog
+		if err := json.Unmarshal(w.Body.Bytes(), &reviewLogs); err != nil {
+			t.Fatalf("failed to unmarshal %s history: %v", direction, err)
+		}
+		if len(reviewLogs) != want {
+			t.Fatalf("expected %d %s reviews, got %d", want, direction, len(reviewLogs))
+		}
+	}
+	if w := doJSON(t, router, http.MethodPut, wordPath, token, map[string]string{"grade": "good", "direction": "sideways"}); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid direction, got %d", w.Code)
+	}
+
+	// New words of the deck get both cards
+	w = doJSON(t, router, http.MethodPost, "/v1/words", token,
+		map[string]string{"word": "la gata", "translation": "die Katze", "category": "animals"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 on create, got %d, body: %s", w.Code, w.Body.String())
+	}
+	w = doJSON(t, router, http.MethodGet, "/v1/decks", token, nil)
+	var decks []repository.DeckSummary
+	if err := json.Unmarshal(w.Body.Bytes(), &decks); err != nil {
+		t.Fatalf("failed to unmarshal decks: %v", err)
+	}
+	if len(decks) != 1 || decks[0].TotalCards != 4 || decks[0].DueCards != 3 {
+		t.Fatalf("unexpected deck summary: %+v", decks)
+	}
+}
+
+func TestUpdatedWordGetsCards(t *testing.T) {
+	router, db := setupAuthTest(t)
+	token := registerAdmin(t, router, db, "ana")
+
+	create := func(body map[string]string) models.Word {
+		t.Helper()
+		w := doJSON(t, router, http.MethodPost, "/v1/words", token, body)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("expected 201 on create, got %d, body: %s", w.Code, w.Body.String())
+		}
+		var word models.Word
+		if err := json.Unmarshal(w.Body.Bytes(), &word); err != nil {
+			t.Fatalf("failed to unmarshal word: %v", err)
+		}
+		return word
+	}
+	update := func(word models.Word, body map[string]string) {
+		t.Helper()
+		path := "/v1/words/" + strconv.FormatUint(uint64(word.ID), 10)
+		if w := doJSON(t, router, http.MethodPatch, path, token, body); w.Code != http.StatusOK {
+			t.Fatalf("expected 200 on update, got %d, body: %s", w.Code, w.Body.String())
+		}
+	}
+	cards := func(word models.Word, cardType models.CardType) int64 {
+		t.Helper()
+		var count int64
+		db.Model(&models.UserWord{}).Where("word_id = ? AND card_type = ?", word.ID, cardType).Count(&count)
+		return count
+	}
+
+	gato := create(map[string]string{"word": "el gato", "translation": "Katze", "category": "pets"})
+	deckPath := "/v1/decks/" + strconv.FormatUint(uint64(*gato.DeckID), 10)
+	if w := doJSON(t, router, http.MethodPatch, deckPath, token, map[string]bool{"gender_cards": true, "cloze_cards": true}); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on deck update, got %d, body: %s", w.Code, w.Body.String())
+	}
+	if cards(gato, models.CardTypeGender) != 0 {
+		t.Fatalf("expected no gender card without an article")
+	}
+
+	// A translation with an article adds the gender card
+	update(gato, map[string]string{"translation": "die Katze"})
+	if got := cards(gato, models.CardTypeGender); got != 1 {
+		t.Fatalf("expected a gender card after adding the article, got %d", got)
+	}
+	// An example sentence adds the cloze card
+	update(gato, map[string]string{"example_sentence": "El gato duerme."})
+	if got := cards(gato, models.CardTypeCloze); got != 1 {
+		t.Fatalf("expected a cloze card after adding an example, got %d", got)
+	}
+
+	// Moving a word into the deck adds the cards the deck turns on
+	perro := create(map[string]string{"word": "el perro", "translation": "der Hund", "category": "animals"})
+	if cards(perro, models.CardTypeGender) != 0 {
+		t.Fatalf("expected no gender card outside the pets deck")
+	}
+	update(perro, map[string]string{"category": "pets"})
+	if got := cards(perro, models.CardTypeGender); got != 1 {
+		t.Fatalf("expected a gender card after moving the word, got %d", got)
+	}
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"learning-cards/internal/anki"
+	"learning-cards/internal/middleware"
+	"learning-cards/internal/services"
+	"learning-cards/internal/utils"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// exportFormats maps the format parameter to the writer, content type and file extension
+var exportFormats = map[string]struct {
+	write       func(io.Writer, *anki.Collection) error
+	contentType string
+	extension   string
+}{
+	"apkg": {anki.WritePackage, "application/apkg", ".apkg"},
+	"tsv":  {anki.WriteText, "text/tab-separated-values; charset=utf-8", ".txt"},
+}
+
+type ExportHandler struct {
+	service *services.ExportService
+}
+
+func NewExportHandler(service *services.ExportService) *ExportHandler {
+	return &ExportHandler{service: service}
+}
+
+// ExportAnki downloads the words of a category, or all of them, with the
+// progress of the current user as Anki package or text file
+func (h *ExportHandler) ExportAnki(c *gin.Context) {
+	format, ok := exportFormats[c.DefaultQuery("format", "apkg")]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format, want apkg or tsv"})
+		return
+	}
+	category := c.Query("category")
+	collection, err := h.service.ExportAnki(middleware.UserID(c), category)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export words"})
+		return
+	}
+
+	// Written to memory first so a failure can still be reported
+	var buf bytes.Buffer
+	if err := format.write(&buf, collection); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write export"})
+		return
+	}
+	name := "learning-cards"
+	if category != "" {
+		name = strings.ReplaceAll(utils.SlugifyPath(category), utils.DeckPathSeparator, "-")
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+name+format.extension+`"`)
+	c.Data(http.StatusOK, format.contentType, buf.Bytes())
+}
@@ -0,0 +1,78 @@
+package handlers_test
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"learning-cards/internal/anki"
+	"learning-cards/internal/models"
+)
+
+func TestExportAnki(t *testing.T) {
+	router, db := setupAuthTest(t)
+	token := registerAdmin(t, router, db, "ana")
+
+	for _, body := range []map[string]any{
+		{"word": "el perro", "translation": "der Hund", "alternatives": []string{"der Köter"}, "category": "animals::pets", "part_of_speech": "noun"},
+		{"word": "la gata", "translation": "die Katze", "category": "animals"},
+		{"word": "rojo", "translation": "rot", "category": "colors"},
+	} {
+		if w := doJSON(t, router, http.MethodPost, "/v1/words", token, body); w.Code != http.StatusCreated {
+			t.Fatalf("expected 201 on create, got %d, body: %s", w.Code, w.Body.String())
+		}
+	}
+	var perro models.Word
+	db.Where("word = ?", "el perro").First(&perro)
+	w := doJSON(t, router, http.MethodPut, "/v1/words/update/"+strconv.FormatUint(uint64(perro.ID), 10), token,
+		map[string]any{"grade": "good", "response_time_ms": 1500})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on update, got %d, body: %s", w.Code, w.Body.String())
+	}
+
+	w = doJSON(t, router, http.MethodGet, "/v1/export/anki?category=animals", token, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on export, got %d, body: %s", w.Code, w.Body.String())
+	}
+	if disposition := w.Header().Get("Content-Disposition"); !strings.Contains(disposition, "animals.apkg") {
+		t.Fatalf("unexpected Content-Disposition %q", disposition)
+	}
+	collection, err := anki.ReadPackage(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()), 1<<20)
+	if err != nil {
+		t.Fatalf("failed to read exported package: %v", err)
+	}
+	// The sub-deck is exported with its parent
+	if len(collection.Notes) != 2 {
+		t.Fatalf("expected 2 notes, got %d", len(collection.Notes))
+	}
+	note := collection.Notes[0]
+	if back, _ := note.Field("Back"); anki.PlainText(back) != "der Hund | der Köter" {
+		t.Fatalf("expected every accepted translation on the back, got %q", back)
+	}
+	if note.Deck != "animals::pets" || len(note.Tags) != 1 || note.Tags[0] != "noun" {
+		t.Fatalf("unexpected note: %+v", note)
+	}
+	if len(note.Cards) != 1 || note.Cards[0].Due.IsZero() || note.Cards[0].Reps != 1 || len(note.Cards[0].Reviews) != 1 {
+		t.Fatalf("expected the answered card with its review, got %+v", note.Cards)
+	}
+	if gata := collection.Notes[1]; len(gata.Cards) != 1 || !gata.Cards[0].Due.IsZero() {
+		t.Fatalf("expected a new card for the unanswered word, got %+v", gata.Cards)
+	}
+
+	w = doJSON(t, router, http.MethodGet, "/v1/export/anki?format=tsv", token, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on text export, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "rojo\trot\tcolors\t") {
+		t.Fatalf("expected every word in the text export, got %q", w.Body.String())
+	}
+
+	if w := doJSON(t, router, http.MethodGet, "/v1/export/anki?category=unknown", token, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown category, got %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodGet, "/v1/export/anki?format=pdf", token, nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown format, got %d", w.Code)
+	}
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"errors"
+	"learning-cards/config"
+	"learning-cards/internal/anki"
+	"learning-cards/internal/middleware"
+	"learning-cards/internal/services"
+	"learning-cards/internal/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportSize limits uploaded packages, Anki collections with media can be large
+const maxImportSize = 256 << 20
+
+// maxCSVImportSize limits uploaded word lists
+const maxCSVImportSize = 16 << 20
+
+type ImportHandler struct {
+	service *services.ImportService
+	cfg     config.ImportConfig
+}
+
+func NewImportHandler(service *services.ImportService, cfg config.ImportConfig) *ImportHandler {
+	return &ImportHandler{service: service, cfg: cfg}
+}
+
+// ImportAnki imports the notes of an uploaded .apkg file as words
+func (h *ImportHandler) ImportAnki(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Package too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A .apkg file is required in the 'file' field"})
+		return
+	}
+	history := false
+	if value := c.PostForm("history"); value != "" {
+		if history, err = strconv.ParseBool(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid history flag"})
+			return
+		}
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read the uploaded file"})
+		return
+	}
+	defer file.Close()
+	collection, err := anki.ReadPackage(file, fileHeader.Size, h.cfg.MaxCollectionSize)
+	if err != nil {
+		if errors.Is(err, anki.ErrInvalidCollection) || errors.Is(err, anki.ErrUnsupportedPackage) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read the package"})
+		return
+	}
+
+	report, err := h.service.ImportAnki(collection, services.AnkiImportOptions{
+		WordField:        c.PostForm("word_field"),
+		TranslationField: c.PostForm("translation_field"),
+		History:          history,
+		UserID:           middleware.UserID(c),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import the package"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// ImportCSV imports the words of an uploaded CSV and reports the problems of
+// every line. With preview=true nothing is written.
+func (h *ImportHandler) ImportCSV(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxCSVImportSize)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A .csv file is required in the 'file' field"})
+		return
+	}
+	preview := false
+	if value := c.PostForm("preview"); value != "" {
+		if preview, err = strconv.ParseBool(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid preview flag"})
+			return
+		}
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read the uploaded file"})
+		return
+	}
+	defer file.Close()
+	records, err := utils.ReadRecords(file)
+	if err != nil {
+		var lineErr *utils.LineError
+		if errors.As(err, &lineErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read the file"})
+		return
+	}
+	rows, err := utils.ConvertRecords(records)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := h.service.ImportCSV(rows, preview)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import the file", "report": report})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,195 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"learning-cards/internal/models"
+	"learning-cards/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func postPackage(t *testing.T, router *gin.Engine, path, token string, content []byte, fields map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for name, value := range fields {
+		writer.WriteField(name, value)
+	}
+	part, err := writer.CreateFormFile("file", "deck.apkg")
+	if err != nil {
+		t.Fatalf("failed to create form file: %v", err)
+	}
+	part.Write(content)
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, path, &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestImportAnki(t *testing.T) {
+	router, db := setupAuthTest(t)
+	token := registerAdmin(t, router, db, "ana")
+
+	w := doJSON(t, router, http.MethodPost, "/v1/words", token,
+		map[string]string{"word": "la manzana", "translation": "die Birne", "category": "food"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 on create, got %d, body: %s", w.Code, w.Body.String())
+	}
+
+	apkg, err := os.ReadFile("../anki/testdata/spanish.apkg")
+	if err != nil {
+		t.Fatalf("failed to read package: %v", err)
+	}
+	w = postPackage(t, router, "/v1/import/anki", token, apkg, map[string]string{"history": "true"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on import, got %d, body: %s", w.Code, w.Body.String())
+	}
+	var report services.ImportReport
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("failed to unmarshal report: %v", err)
+	}
+	// The empty and the cloze note are skipped, as is the second perro with the same translation
+	if report.Imported != 2 || report.Skipped != 3 || report.Conflicting != 1 || report.Reviews != 3 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if len(report.Conflicts) != 1 || report.Conflicts[0].Translation != "der Apfel" || report.Conflicts[0].ExistingTranslation != "die Birne" {
+		t.Fatalf("unexpected conflicts: %+v", report.Conflicts)
+	}
+
+	var perro models.Word
+	if err := db.Where("word = ?", "el perro").First(&perro).Error; err != nil {
+		t.Fatalf("expected el perro to be imported: %v", err)
+	}
+	if perro.Translation != "der Hund" || perro.Category != "Spanish::Animals" {
+		t.Fatalf("unexpected word: %+v", perro)
+	}
+
+	// Two good answers and an easy one move the card to the last box
+	var userWord models.UserWord
+	db.Joins("JOIN users ON users.id = user_words.user_id").
+		Where("users.username = ? AND word_id = ? AND direction = ?", "ana", perro.ID, models.DirectionForward).
+		First(&userWord)
+	if userWord.BoxNumber != 5 || userWord.CorrectAttempts != 3 {
+		t.Fatalf("expected the history to be replayed, got %+v", userWord)
+	}
+	var logs int64
+	db.Model(&models.ReviewLog{}).Where("user_word_id = ?", userWord.ID).Count(&logs)
+	if logs != 3 {
+		t.Fatalf("expected 3 review logs, got %d", logs)
+	}
+
+	// Importing again changes nothing
+	w = postPackage(t, router, "/v1/import/anki", token, apkg, map[string]string{"history": "true"})
+	json.Unmarshal(w.Body.Bytes(), &report)
+	if report.Imported != 0 || report.Reviews != 0 || report.Skipped != 5 {
+		t.Fatalf("unexpected report on second import: %+v", report)
+	}
+
+	w = postPackage(t, router, "/v1/import/anki", token, []byte("not a zip"), nil)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid package, got %d", w.Code)
+	}
+}
+
+func TestImportCSV(t *testing.T) {
+	router, db := setupAuthTest(t)
+	token := registerAdmin(t, router, db, "ana")
+
+	w := doJSON(t, router, http.MethodPost, "/v1/words", token,
+		map[string]string{"word": "el perro", "translation": "der Hund", "category": "animals"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 on create, got %d, body: %s", w.Code, w.Body.String())
+	}
+
+	content := []byte("word,translation,category,note\n" +
+		"la gata,die Katze | die Mieze,animals,\n" +
+		"rojo,rot\n" +
+		",blau,colors,\n" +
+		"El Perro,der Hund,animals,\n" +
+		"LA GATA,die Katze,animals,\n" +
+		"verde,grün,,\n" +
+		"\"el\nrío\",\"der Fluss\",nature," + strings.Repeat("x", 1001) + "\n" +
+		"amarillo,gelb,colors,\n")
+
+	countWords := func() int64 {
+		var count int64
+		db.Model(&models.Word{}).Count(&count)
+		return count
+	}
+	before := countWords()
+
+	w = postPackage(t, router, "/v1/import/csv", token, content, map[string]string{"preview": "true"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on preview, got %d, body: %s", w.Code, w.Body.String())
+	}
+	var preview services.CSVImportReport
+	if err := json.Unmarshal(w.Body.Bytes(), &preview); err != nil {
+		t.Fatalf("failed to unmarshal report: %v", err)
+	}
+	if !preview.Preview || preview.Lines != 8 || preview.Imported != 2 || preview.Skipped != 2 || preview.Invalid != 4 {
+		t.Fatalf("unexpected preview report: %+v", preview)
+	}
+	if got := countWords(); got != before {
+		t.Fatalf("expected the preview to write nothing, word count went from %d to %d", before, got)
+	}
+
+	issues := make(map[int][]services.CSVIssue)
+	for _, issue := range preview.Issues {
+		issues[issue.Line] = append(issues[issue.Line], issue)
+	}
+	expected := map[int]string{
+		3: services.ProblemFieldCount,
+		4: services.ProblemEmpty,
+		5: services.ProblemDuplicate,
+		6: services.ProblemDuplicate,
+		7: services.ProblemEmpty,
+		8: services.ProblemTooLong,
+	}
+	for line, problem := range expected {
+		if len(issues[line]) != 1 || issues[line][0].Problem != problem {
+			t.Fatalf("expected a %s issue on line %d, got %+v", problem, line, issues[line])
+		}
+	}
+	if issues[5][0].ExistingWordID == 0 || issues[6][0].DuplicateOf != 2 || issues[8][0].Field != "note" {
+		t.Fatalf("unexpected issue details: %+v", preview.Issues)
+	}
+
+	w = postPackage(t, router, "/v1/import/csv", token, content, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on import, got %d, body: %s", w.Code, w.Body.String())
+	}
+	var report services.CSVImportReport
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("failed to unmarshal report: %v", err)
+	}
+	if report.Preview || report.Imported != 2 || report.Skipped != 2 || report.Invalid != 4 || len(report.Issues) != len(preview.Issues) {
+		t.Fatalf("expected the import to match the preview, got %+v", report)
+	}
+	if got := countWords(); got != before+2 {
+		t.Fatalf("expected 2 new words, word count went from %d to %d", before, got)
+	}
+	var gata models.Word
+	if err := db.Preload("Translations").Where("word = ?", "la gata").First(&gata).Error; err != nil {
+		t.Fatalf("expected la gata to be imported: %v", err)
+	}
+	if len(gata.Translations) != 2 || gata.Translation != "die Katze" {
+		t.Fatalf("unexpected translations of la gata: %+v", gata.Translations)
+	}
+
+	w = postPackage(t, router, "/v1/import/csv", token, []byte("word,translation\nrojo,rot\n"), nil)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without category column, got %d, body: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"errors"
+	"learning-cards/internal/media"
+	"learning-cards/internal/services"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// mediaCacheControl lets clients keep media forever, since a name never changes content
+const mediaCacheControl = "private, max-age=31536000, immutable"
+
+// multipartOverhead leaves room for the multipart headers around the file
+const multipartOverhead = 64 << 10
+
+type MediaHandler struct {
+	service *services.MediaService
+}
+
+func NewMediaHandler(service *services.MediaService) *MediaHandler {
+	return &MediaHandler{service: service}
+}
+
+func (h *MediaHandler) GetWordMedia(c *gin.Context) {
+	wordID, ok := wordIDParam(c)
+	if !ok {
+		return
+	}
+	items, err := h.service.GetWordMedia(wordID)
+	if err != nil {
+		respondMediaError(c, err, "Failed to retrieve media.")
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+func (h *MediaHandler) UploadMedia(c *gin.Context) {
+	wordID, ok := wordIDParam(c)
+	if !ok {
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.service.MaxSize()+multipartOverhead)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondMediaError(c, services.ErrMediaTooLarge, "")
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A file is required in the 'file' field"})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read the uploaded file"})
+		return
+	}
+	defer file.Close()
+
+	item, err := h.service.Upload(wordID, fileHeader.Filename, file)
+	if err != nil {
+		respondMediaError(c, err, "Failed to store media")
+		return
+	}
+	c.JSON(http.StatusCreated, item)
+}
+
+// ServeMedia serves a stored file. Names are content hashes, so the hash
+// doubles as ETag and the response can be cached indefinitely.
+func (h *MediaHandler) ServeMedia(c *gin.Context) {
+	file, item, err := h.service.Open(c.Param("name"))
+	if err != nil {
+		respondMediaError(c, err, "Failed to open media")
+		return
+	}
+	defer file.Close()
+
+	c.Header("Content-Type", item.MimeType)
+	c.Header("ETag", `"`+item.Hash+`"`)
+	c.Header("Cache-Control", mediaCacheControl)
+	c.Header("X-Content-Type-Options", "nosniff")
+	http.ServeContent(c.Writer, c.Request, item.Name, item.CreatedAt, file)
+}
+
+func (h *MediaHandler) DeleteMedia(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("mediaID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid media ID"})
+		return
+	}
+	if err := h.service.DeleteMedia(uint(id)); err != nil {
+		respondMediaError(c, err, "Failed to delete media")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Media deleted successfully"})
+}
+
+func respondMediaError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, media.ErrInvalidName), errors.Is(err, os.ErrNotExist):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+	case errors.Is(err, services.ErrMediaTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case errors.Is(err, media.ErrUnsupportedType):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
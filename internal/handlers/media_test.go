@@ -0,0 +1,131 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"learning-cards/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pngHeader is enough of a PNG file for content sniffing
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00")
+
+func uploadMedia(t *testing.T, router *gin.Engine, path, token, filename string, content []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatalf("failed to create form file: %v", err)
+	}
+	part.Write(content)
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, path, &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestWordMedia(t *testing.T) {
+	router, db := setupAuthTest(t)
+	token := registerAdmin(t, router, db, "ana")
+
+	w := doJSON(t, router, http.MethodPost, "/v1/words", token,
+		map[string]string{"word": "el perro", "translation": "der Hund", "category": "animals"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 on create, got %d, body: %s", w.Code, w.Body.String())
+	}
+	var word models.Word
+	if err := json.Unmarshal(w.Body.Bytes(), &word); err != nil {
+		t.Fatalf("failed to unmarshal word: %v", err)
+	}
+	path := "/v1/words/" + strconv.FormatUint(uint64(word.ID), 10) + "/media"
+
+	w = uploadMedia(t, router, path, token, "perro.png", pngHeader)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 on upload, got %d, body: %s", w.Code, w.Body.String())
+	}
+	var uploaded models.Media
+	if err := json.Unmarshal(w.Body.Bytes(), &uploaded); err != nil {
+		t.Fatalf("failed to unmarshal media: %v", err)
+	}
+	if uploaded.Kind != "image" || uploaded.MimeType != "image/png" || uploaded.Filename != "perro.png" {
+		t.Fatalf("unexpected media: %+v", uploaded)
+	}
+
+	// The same content is not stored twice
+	w = uploadMedia(t, router, path, token, "copy.png", pngHeader)
+	var again models.Media
+	json.Unmarshal(w.Body.Bytes(), &again)
+	if again.ID != uploaded.ID {
+		t.Fatalf("expected the existing media on re-upload, got %+v", again)
+	}
+	var count int64
+	db.Model(&models.Media{}).Count(&count)
+	if count != 1 {
+		t.Fatalf("expected one media row, got %d", count)
+	}
+
+	w = doJSON(t, router, http.MethodGet, "/v1/words/"+strconv.FormatUint(uint64(word.ID), 10), token, nil)
+	var fetched models.Word
+	json.Unmarshal(w.Body.Bytes(), &fetched)
+	if len(fetched.Media) != 1 || fetched.Media[0].Name != uploaded.Name {
+		t.Fatalf("expected the word to list its media, got %+v", fetched.Media)
+	}
+
+	w = doJSON(t, router, http.MethodGet, "/v1/media/"+uploaded.Name, token, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 serving media, got %d, body: %s", w.Code, w.Body.String())
+	}
+	if !bytes.Equal(w.Body.Bytes(), pngHeader) {
+		t.Fatalf("served content differs from the upload")
+	}
+	if w.Header().Get("Content-Type") != "image/png" || w.Header().Get("Cache-Control") == "" {
+		t.Fatalf("unexpected headers: %v", w.Header())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/media/"+uploaded.Name, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("If-None-Match", w.Header().Get("ETag"))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("expected 304 for a matching ETag, got %d", w.Code)
+	}
+
+	w = uploadMedia(t, router, path, token, "notes.txt", []byte("just some text"))
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected 415 for text, got %d", w.Code)
+	}
+	w = uploadMedia(t, router, path, token, "big.png", append(pngHeader, make([]byte, 2<<10)...))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413 for a large file, got %d", w.Code)
+	}
+	w = uploadMedia(t, router, "/v1/words/9999/media", token, "perro.png", pngHeader)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown word, got %d", w.Code)
+	}
+	w = doJSON(t, router, http.MethodGet, "/v1/media/../../etc/passwd", token, nil)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an invalid name, got %d", w.Code)
+	}
+
+	w = doJSON(t, router, http.MethodDelete, "/v1/media/"+strconv.FormatUint(uint64(uploaded.ID), 10), token, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on delete, got %d, body: %s", w.Code, w.Body.String())
+	}
+	w = doJSON(t, router, http.MethodGet, "/v1/media/"+uploaded.Name, token, nil)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 after delete, got %d", w.Code)
+	}
+}
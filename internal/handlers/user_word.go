@@ -2,27 +2,35 @@ package handlers
 
 import (
 	"errors"
+	"learning-cards/internal/answer"
+	"learning-cards/internal/middleware"
+	"learning-cards/internal/models"
 	"learning-cards/internal/services"
-	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/jackc/pgconn"
+	"gorm.io/gorm"
 )
 
+// maxAnswerBodySize limits the body of an answer, which holds little more than the typed text
+const maxAnswerBodySize = 64 << 10
+
 type UserWordHandler struct {
-	service *services.UserWordService
+	service     *services.UserWordService
+	userService *services.UserService
 }
 
-func NewUserWordHandler(service *services.UserWordService) *UserWordHandler {
+func NewUserWordHandler(service *services.UserWordService, userService *services.UserService) *UserWordHandler {
 	return &UserWordHandler{
-		service: service,
+		service:     service,
+		userService: userService,
 	}
 }
 
 func (h *UserWordHandler) GetUserWords(c *gin.Context) {
-	userWords, err := h.service.GetUserWords()
+	userWords, err := h.service.GetUserWords(middleware.UserID(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user words."})
 		return
@@ -31,7 +39,7 @@ func (h *UserWordHandler) GetUserWords(c *gin.Context) {
 
 }
 func (h *UserWordHandler) GetUserWordDueToday(c *gin.Context) {
-	userWords, err := h.service.GetUserWordsDueToday()
+	userWords, err := h.service.GetUserWordsDueToday(middleware.UserID(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user words for today."})
 		return
@@ -41,9 +49,15 @@ func (h *UserWordHandler) GetUserWordDueToday(c *gin.Context) {
 
 func (h *UserWordHandler) GetUserWordsByCategory(c *gin.Context) {
 	category := c.Param("category")
-	userWords, err := h.service.GetUserWordByCategory(category)
+	includeDescendants, err := strconv.ParseBool(c.DefaultQuery("descendants", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid descendants flag"})
+		return
+	}
+	userWords, err := h.service.GetUserWordByCategory(middleware.UserID(c), category, includeDescendants)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user words for category."})
+		return
 	}
 	c.JSON(http.StatusOK, userWords)
 }
@@ -57,16 +71,37 @@ func (h *UserWordHandler) UpdateUserWord(c *gin.Context) {
 		return
 	}
 
+	// Clients either send a grade or, for backwards compatibility, the learned flag
 	var requestBody struct {
-		Learned bool `json:"learned"`
+		Learned        *bool        `json:"learned"`
+		Grade          models.Grade `json:"grade"`
+		CardType       string       `json:"card_type"`
+		Direction      string       `json:"direction"`
+		ResponseTimeMs uint         `json:"response_time_ms"`
 	}
 	if err := c.ShouldBindJSON(&requestBody); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	err = h.service.UpdateUserWord(uint(id), requestBody.Learned)
+	grade := requestBody.Grade
+	if grade == 0 {
+		// Like the legacy form, a body without grade or learned flag was not learned
+		grade = models.GradeFromLearned(requestBody.Learned != nil && *requestBody.Learned)
+	}
+	card, err := models.ParseCard(uint(id), requestBody.CardType, requestBody.Direction)
 	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	responseTime := time.Duration(requestBody.ResponseTimeMs) * time.Millisecond
+
+	err = h.service.UpdateUserWord(middleware.UserID(c), card, grade, responseTime)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Card not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update word"})
 		return
 	}
@@ -74,45 +109,88 @@ func (h *UserWordHandler) UpdateUserWord(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Word updated successfully"})
 }
 
-func (h *UserWordHandler) SyncUserWords() error {
-	allWords, err := h.service.GetAllWords()
+// AnswerWord grades a typed answer instead of trusting the client to report
+// whether the word was learned
+func (h *UserWordHandler) AnswerWord(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("wordID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid word ID"})
+		return
+	}
+
+	var requestBody struct {
+		Answer         string `json:"answer"`
+		CardType       string `json:"card_type"`
+		Direction      string `json:"direction"`
+		ResponseTimeMs uint   `json:"response_time_ms"`
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxAnswerBodySize)
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Answer too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	card, err := models.ParseCard(uint(id), requestBody.CardType, requestBody.Direction)
 	if err != nil {
-		return errors.New("failed to retrieve all words")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
+	responseTime := time.Duration(requestBody.ResponseTimeMs) * time.Millisecond
 
-	userWords, err := h.service.GetUserWords()
+	result, err := h.service.AnswerCard(middleware.UserID(c), card, requestBody.Answer, responseTime)
 	if err != nil {
-		return errors.New("failed to retrieve user words")
-	}
-
-	existingUserWords := make(map[uint]struct{})
-	for _, userWord := range userWords {
-		existingUserWords[userWord.WordID] = struct{}{}
-	}
-
-	for _, word := range allWords {
-		if _, exists := existingUserWords[word.ID]; !exists {
-			existsInUserWords, err := h.service.CheckUserWordExists(word.ID)
-			if err != nil {
-				log.Printf("Error checking existence of word %d: %v", word.ID, err)
-				continue
-			}
-
-			if !existsInUserWords {
-				err = h.service.AddUserWord(word.ID)
-				if err != nil {
-					var pgErr *pgconn.PgError
-					if errors.As(err, &pgErr) {
-						log.Printf("PostgreSQL error code: %s", pgErr.Code)
-						if pgErr.Code == "23505" {
-							log.Printf("Word %d already exists in user words, skipping.", word.ID)
-							continue
-						}
-					}
-					return errors.New("failed to add word to user words")
-				}
-			}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Card not found"})
+			return
+		}
+		if errors.Is(err, services.ErrNoCloze) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrAnswerTooLong) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
 		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check answer"})
+		return
 	}
-	return nil
+
+	c.JSON(http.StatusOK, struct {
+		answer.Result
+		Grade models.Grade `json:"grade"`
+	}{result, result.Verdict.Grade()})
+}
+
+func (h *UserWordHandler) GetReviewHistory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("wordID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid word ID"})
+		return
+	}
+
+	card, err := models.ParseCard(uint(id), c.Query("card_type"), c.Query("direction"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reviewLogs, err := h.service.GetReviewHistory(middleware.UserID(c), card)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Word not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve review history."})
+		return
+	}
+	c.JSON(http.StatusOK, reviewLogs)
+}
+
+// SyncUserWords makes sure every user has a user word for every word
+func (h *UserWordHandler) SyncUserWords() error {
+	return h.service.SyncAllUsers()
 }
@@ -10,8 +10,10 @@ import (
 	"time"
 
 	"learning-cards/internal/handlers"
+	"learning-cards/internal/middleware"
 	"learning-cards/internal/models"
 	"learning-cards/internal/repository"
+	"learning-cards/internal/scheduler"
 	"learning-cards/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -30,23 +32,36 @@ func setupTest(t *testing.T) (*handlers.UserWordHandler, *gorm.DB) {
 	}
 
 	// Auto-migrate models
-	if err := db.AutoMigrate(&models.Word{}, &models.UserWord{}); err != nil {
+	if err := db.AutoMigrate(&models.User{}, &models.Deck{}, &models.Word{}, &models.WordTranslation{}, &models.Media{}, &models.UserWord{}, &models.ReviewLog{}); err != nil {
 		t.Fatalf("auto migrate failed: %v", err)
 	}
 
 	// Create repository/service/handler
-	repo := repository.NewUserWordRepository(db)
+	repo := repository.NewUserWordRepository(db, scheduler.Leitner{})
 	svc := services.NewUserWordService(repo)
-	handler := handlers.NewUserWordHandler(svc)
+	userSvc := services.NewUserService(repository.NewUserRepository(db))
+	handler := handlers.NewUserWordHandler(svc, userSvc)
 
 	return handler, db
 }
 
-// seedData inserts a few words and one user_word (for the first word) into the DB.
-// Returns the created words slice.
-func seedData(t *testing.T, db *gorm.DB) []models.Word {
+// newRouter returns a router whose requests act for the given user.
+func newRouter(userID uint) *gin.Engine {
+	router := gin.New()
+	router.Use(func(c *gin.Context) { middleware.SetUserID(c, userID) })
+	return router
+}
+
+// seedData inserts a user, a few words and one user_word (for the first word) into the DB.
+// Returns the created user and words slice.
+func seedData(t *testing.T, db *gorm.DB) (models.User, []models.Word) {
 	t.Helper()
 
+	user := models.User{Username: "learner"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("failed to seed user: %v", err)
+	}
+
 	words := []models.Word{
 		{Word: "cat", Translation: "gato", Category: "animals", CreatedAt: time.Now()},
 		{Word: "dog", Translation: "perro", Category: "animals", CreatedAt: time.Now()},
@@ -59,6 +74,7 @@ func seedData(t *testing.T, db *gorm.DB) []models.Word {
 
 	// Create a user_word for the first word, set NextReview in the past so it's due today
 	userWord := models.UserWord{
+		UserID:            user.ID,
 		WordID:            words[0].ID,
 		BoxNumber:         1,
 		LastReview:        time.Now().Add(-48 * time.Hour),
@@ -71,7 +87,7 @@ func seedData(t *testing.T, db *gorm.DB) []models.Word {
 		t.Fatalf("failed to seed user word: %v", err)
 	}
 
-	return words
+	return user, words
 }
 
 func TestGetUserWordsHandler(t *testing.T) {
@@ -82,9 +98,9 @@ func TestGetUserWordsHandler(t *testing.T) {
 		_ = sqlDB.Close()
 	}()
 
-	seedData(t, db)
+	user, _ := seedData(t, db)
 
-	router := gin.New()
+	router := newRouter(user.ID)
 	router.GET("/userwords", handler.GetUserWords)
 
 	req := httptest.NewRequest(http.MethodGet, "/userwords", nil)
@@ -113,10 +129,11 @@ func TestGetUserWordsByCategoryHandler(t *testing.T) {
 		_ = sqlDB.Close()
 	}()
 
-	words := seedData(t, db)
+	user, words := seedData(t, db)
 
 	// Also add a user_word for the 'apple' word and make it due
 	if err := db.Create(&models.UserWord{
+		UserID:            user.ID,
 		WordID:            words[2].ID,
 		BoxNumber:         1,
 		LastReview:        time.Now().Add(-48 * time.Hour),
@@ -127,7 +144,7 @@ func TestGetUserWordsByCategoryHandler(t *testing.T) {
 		t.Fatalf("failed to seed additional user word: %v", err)
 	}
 
-	router := gin.New()
+	router := newRouter(user.ID)
 	router.GET("/userwords/category/:category", handler.GetUserWordsByCategory)
 
 	req := httptest.NewRequest(http.MethodGet, "/userwords/category/animals", nil)
@@ -162,7 +179,7 @@ func TestUpdateUserWordHandler(t *testing.T) {
 		_ = sqlDB.Close()
 	}()
 
-	words := seedData(t, db)
+	user, words := seedData(t, db)
 
 	// Ensure there's a user_word for words[0]
 	var before models.UserWord
@@ -174,7 +191,7 @@ func TestUpdateUserWordHandler(t *testing.T) {
 	body := map[string]bool{"learned": true}
 	bs, _ := json.Marshal(body)
 
-	router := gin.New()
+	router := newRouter(user.ID)
 	router.PUT("/userwords/:wordID", handler.UpdateUserWord)
 
 	req := httptest.NewRequest(http.MethodPut, "/userwords/"+strconv.FormatUint(uint64(words[0].ID), 10), bytes.NewReader(bs))
@@ -197,6 +214,139 @@ func TestUpdateUserWordHandler(t *testing.T) {
 	}
 }
 
+func TestUpdateUserWordHandlerWithGrade(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler, db := setupTest(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		_ = sqlDB.Close()
+	}()
+
+	user, words := seedData(t, db)
+
+	router := newRouter(user.ID)
+	router.PUT("/userwords/:wordID", handler.UpdateUserWord)
+	path := "/userwords/" + strconv.FormatUint(uint64(words[0].ID), 10)
+
+	// An easy answer skips a box: 1 -> 3
+	req := httptest.NewRequest(http.MethodPut, path, bytes.NewReader([]byte(`{"grade":"easy","response_time_ms":1500}`)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d, body: %s", w.Code, w.Body.String())
+	}
+
+	var after models.UserWord
+	if err := db.Where("word_id = ?", words[0].ID).First(&after).Error; err != nil {
+		t.Fatalf("failed to fetch user word after update: %v", err)
+	}
+	if after.BoxNumber != 3 {
+		t.Fatalf("expected BoxNumber 3 after an easy answer, got %d", after.BoxNumber)
+	}
+
+	// Grades may also be sent as numbers, 2 is hard and keeps the box
+	for _, body := range []string{`{"grade":2}`, `{"grade":"hard"}`} {
+		req = httptest.NewRequest(http.MethodPut, path, bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200 for body %s, got %d, body: %s", body, w.Code, w.Body.String())
+		}
+		if err := db.Where("word_id = ?", words[0].ID).First(&after).Error; err != nil {
+			t.Fatalf("failed to fetch user word after update: %v", err)
+		}
+		if after.BoxNumber != 3 {
+			t.Fatalf("expected BoxNumber 3 after a hard answer %s, got %d", body, after.BoxNumber)
+		}
+	}
+
+	// Unknown grades are rejected
+	for _, body := range []string{`{"grade":"perfect"}`, `{"grade":0}`, `{"grade":5}`, `{"grade":true}`} {
+		req = httptest.NewRequest(http.MethodPut, path, bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400 for body %s, got %d", body, w.Code)
+		}
+	}
+
+	// Like the legacy form without learned flag, an empty body was not learned
+	req = httptest.NewRequest(http.MethodPut, path, bytes.NewReader([]byte(`{}`)))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200 for an empty body, got %d, body: %s", w.Code, w.Body.String())
+	}
+	if err := db.Where("word_id = ?", words[0].ID).First(&after).Error; err != nil {
+		t.Fatalf("failed to fetch user word after update: %v", err)
+	}
+	if after.BoxNumber != 1 {
+		t.Fatalf("expected BoxNumber 1 after an empty body, got %d", after.BoxNumber)
+	}
+}
+
+func TestGetReviewHistoryHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler, db := setupTest(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		_ = sqlDB.Close()
+	}()
+
+	user, words := seedData(t, db)
+
+	router := newRouter(user.ID)
+	router.PUT("/userwords/:wordID", handler.UpdateUserWord)
+	router.GET("/userwords/:wordID/history", handler.GetReviewHistory)
+	path := "/userwords/" + strconv.FormatUint(uint64(words[0].ID), 10)
+
+	for _, body := range []string{`{"grade":"good"}`, `{"learned":false}`} {
+		req := httptest.NewRequest(http.MethodPut, path, bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d, body: %s", w.Code, w.Body.String())
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, path+"/history", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d, body: %s", w.Code, w.Body.String())
+	}
+
+	var got []models.ReviewLog
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 review logs, got %d", len(got))
+	}
+	if got[0].Grade != models.GradeGood || got[0].PreviousBox != 1 || got[0].NewBox != 2 {
+		t.Fatalf("unexpected first review log: %+v", got[0])
+	}
+	if got[1].Grade != models.GradeAgain || got[1].PreviousBox != 2 || got[1].NewBox != 1 {
+		t.Fatalf("unexpected second review log: %+v", got[1])
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/userwords/9999/history", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404 for unknown word, got %d", w.Code)
+	}
+}
+
 func TestSyncUserWordsAddsMissing(t *testing.T) {
 	_, db := setupTest(t)
 	defer func() {
@@ -204,6 +354,12 @@ func TestSyncUserWordsAddsMissing(t *testing.T) {
 		_ = sqlDB.Close()
 	}()
 
+	// Two learners, each needs their own user_words
+	users := []models.User{{Username: "ana"}, {Username: "ben"}}
+	if err := db.Create(&users).Error; err != nil {
+		t.Fatalf("failed to seed users: %v", err)
+	}
+
 	// Seed words but do not create user_words for all of them
 	words := []models.Word{
 		{Word: "sun", Translation: "sol", Category: "nature", CreatedAt: time.Now()},
@@ -214,8 +370,9 @@ func TestSyncUserWordsAddsMissing(t *testing.T) {
 		t.Fatalf("failed to seed words: %v", err)
 	}
 
-	// Only create a user_word for the first word
+	// Only create a user_word for the first word of the first user
 	if err := db.Create(&models.UserWord{
+		UserID:            users[0].ID,
 		WordID:            words[0].ID,
 		BoxNumber:         1,
 		LastReview:        time.Now(),
@@ -227,22 +384,25 @@ func TestSyncUserWordsAddsMissing(t *testing.T) {
 	}
 
 	// Handler created by setupTest is not used here; recreate the handler wired to this DB.
-	repo := repository.NewUserWordRepository(db)
+	repo := repository.NewUserWordRepository(db, scheduler.Leitner{})
 	svc := services.NewUserWordService(repo)
-	h := handlers.NewUserWordHandler(svc)
+	userSvc := services.NewUserService(repository.NewUserRepository(db))
+	h := handlers.NewUserWordHandler(svc, userSvc)
 
 	// Call SyncUserWords which should add user_words for the missing words
 	if err := h.SyncUserWords(); err != nil {
 		t.Fatalf("SyncUserWords returned error: %v", err)
 	}
 
-	// Count user_words and compare to words length
-	var count int64
-	if err := db.Model(&models.UserWord{}).Count(&count).Error; err != nil {
-		t.Fatalf("failed to count user_words: %v", err)
-	}
+	// Count user_words per user and compare to words length
+	for _, user := range users {
+		var count int64
+		if err := db.Model(&models.UserWord{}).Where("user_id = ?", user.ID).Count(&count).Error; err != nil {
+			t.Fatalf("failed to count user_words: %v", err)
+		}
 
-	if int(count) != len(words) {
-		t.Fatalf("expected %d user_words after sync, got %d", len(words), count)
+		if int(count) != len(words) {
+			t.Fatalf("expected %d user_words for %s after sync, got %d", len(words), user.Username, count)
+		}
 	}
 }
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"errors"
+	"learning-cards/internal/models"
+	"learning-cards/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type WordHandler struct {
+	service *services.WordService
+}
+
+func NewWordHandler(service *services.WordService) *WordHandler {
+	return &WordHandler{service: service}
+}
+
+func (h *WordHandler) GetWords(c *gin.Context) {
+	includeDescendants, err := strconv.ParseBool(c.DefaultQuery("descendants", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid descendants flag"})
+		return
+	}
+	words, err := h.service.GetWords(c.Query("category"), includeDescendants)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve words."})
+		return
+	}
+	c.JSON(http.StatusOK, words)
+}
+
+func (h *WordHandler) GetWord(c *gin.Context) {
+	id, ok := wordIDParam(c)
+	if !ok {
+		return
+	}
+	word, err := h.service.GetWord(id)
+	if err != nil {
+		respondWordError(c, err, "Failed to retrieve word.")
+		return
+	}
+	c.JSON(http.StatusOK, word)
+}
+
+func (h *WordHandler) CreateWord(c *gin.Context) {
+	var requestBody struct {
+		Word               string   `json:"word"`
+		Translation        string   `json:"translation"`
+		Alternatives       []string `json:"alternatives"`
+		Category           string   `json:"category"`
+		ExampleSentence    string   `json:"example_sentence"`
+		ExampleTranslation string   `json:"example_translation"`
+		Note               string   `json:"note"`
+		PartOfSpeech       string   `json:"part_of_speech"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	word := models.Word{
+		Word:               requestBody.Word,
+		Category:           requestBody.Category,
+		ExampleSentence:    requestBody.ExampleSentence,
+		ExampleTranslation: requestBody.ExampleTranslation,
+		Note:               requestBody.Note,
+		PartOfSpeech:       requestBody.PartOfSpeech,
+	}
+	word.SetTranslations(requestBody.Translation, requestBody.Alternatives)
+	word, err := h.service.CreateWord(word)
+	if err != nil {
+		respondWordError(c, err, "Failed to create word")
+		return
+	}
+	c.JSON(http.StatusCreated, word)
+}
+
+func (h *WordHandler) UpdateWord(c *gin.Context) {
+	id, ok := wordIDParam(c)
+	if !ok {
+		return
+	}
+	var requestBody struct {
+		Word               *string   `json:"word"`
+		Translation        *string   `json:"translation"`
+		Alternatives       *[]string `json:"alternatives"`
+		Category           *string   `json:"category"`
+		ExampleSentence    *string   `json:"example_sentence"`
+		ExampleTranslation *string   `json:"example_translation"`
+		Note               *string   `json:"note"`
+		PartOfSpeech       *string   `json:"part_of_speech"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	word, err := h.service.UpdateWord(id, services.WordChanges{
+		Word:               requestBody.Word,
+		Translation:        requestBody.Translation,
+		Alternatives:       requestBody.Alternatives,
+		Category:           requestBody.Category,
+		ExampleSentence:    requestBody.ExampleSentence,
+		ExampleTranslation: requestBody.ExampleTranslation,
+		Note:               requestBody.Note,
+		PartOfSpeech:       requestBody.PartOfSpeech,
+	})
+	if err != nil {
+		respondWordError(c, err, "Failed to update word")
+		return
+	}
+	c.JSON(http.StatusOK, word)
+}
+
+func (h *WordHandler) DeleteWord(c *gin.Context) {
+	id, ok := wordIDParam(c)
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteWord(id); err != nil {
+		respondWordError(c, err, "Failed to delete word")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Word deleted successfully"})
+}
+
+// wordIDParam parses the wordID path parameter and answers 400 when it is invalid
+func wordIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("wordID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid word ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondWordError(c *gin.Context, err error, message string) {
+	var duplicate *services.DuplicateWordError
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Word not found"})
+	case errors.Is(err, services.ErrInvalidWord):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.As(err, &duplicate):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "word_id": duplicate.Existing.ID})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,236 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"learning-cards/internal/models"
+)
+
+func TestWordCRUD(t *testing.T) {
+	router, db := setupAuthTest(t)
+	token := registerAdmin(t, router, db, "ana")
+
+	w := doJSON(t, router, http.MethodPost, "/v1/words", token,
+		map[string]string{"word": " el ratón ", "translation": "die Maus", "category": "animals"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 on create, got %d, body: %s", w.Code, w.Body.String())
+	}
+	var created models.Word
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("failed to unmarshal word: %v", err)
+	}
+	if created.Word != "el ratón" {
+		t.Fatalf("expected word to be trimmed, got %q", created.Word)
+	}
+	path := "/v1/words/" + strconv.FormatUint(uint64(created.ID), 10)
+
+	// The new word can be studied right away
+	var userWordCount int64
+	db.Model(&models.UserWord{}).Where("word_id = ?", created.ID).Count(&userWordCount)
+	if userWordCount != 1 {
+		t.Fatalf("expected a user word for the new word, got %d", userWordCount)
+	}
+
+	invalid := []map[string]string{
+		{"word": "el gato", "translation": ""},
+		{"word": "", "translation": "die Katze"},
+		{"word": strings.Repeat("a", 256), "translation": "die Katze"},
+	}
+	for _, body := range invalid {
+		if w := doJSON(t, router, http.MethodPost, "/v1/words", token, body); w.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %v, got %d", body, w.Code)
+		}
+	}
+	duplicate := map[string]string{"word": "El Ratón", "translation": "die Maus"}
+	if w := doJSON(t, router, http.MethodPost, "/v1/words", token, duplicate); w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for duplicate word, got %d", w.Code)
+	}
+
+	w = doJSON(t, router, http.MethodPatch, path, token, map[string]string{"translation": "die Maus (Tier)"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on patch, got %d, body: %s", w.Code, w.Body.String())
+	}
+	w = doJSON(t, router, http.MethodGet, path, token, nil)
+	var fetched models.Word
+	if err := json.Unmarshal(w.Body.Bytes(), &fetched); err != nil {
+		t.Fatalf("failed to unmarshal word: %v", err)
+	}
+	if fetched.Translation != "die Maus (Tier)" || fetched.Word != "el ratón" || fetched.Category != "animals" {
+		t.Fatalf("expected only the translation to change, got %+v", fetched)
+	}
+
+	// Review once so there is history to clean up
+	if w := doJSON(t, router, http.MethodPut, "/v1/words/update/"+strconv.FormatUint(uint64(created.ID), 10), token,
+		map[string]string{"grade": "good"}); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on review, got %d", w.Code)
+	}
+
+	if w := doJSON(t, router, http.MethodDelete, path, token, nil); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on delete, got %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodGet, path, token, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 after delete, got %d", w.Code)
+	}
+	var remaining int64
+	db.Model(&models.UserWord{}).Where("word_id = ?", created.ID).Count(&remaining)
+	if remaining != 0 {
+		t.Fatalf("expected user words to be deleted with the word, got %d", remaining)
+	}
+	db.Model(&models.ReviewLog{}).Count(&remaining)
+	if remaining != 0 {
+		t.Fatalf("expected review logs to be deleted with the word, got %d", remaining)
+	}
+}
+
+func TestSharedWritesRequireAdmin(t *testing.T) {
+	router, db := setupAuthTest(t)
+	admin := registerAdmin(t, router, db, "ana")
+	token := registerAndLogin(t, router, "ben")
+
+	w := doJSON(t, router, http.MethodPost, "/v1/words", admin,
+		map[string]string{"word": "el perro", "translation": "der Hund", "category": "animals"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 on create, got %d, body: %s", w.Code, w.Body.String())
+	}
+	var word models.Word
+	if err := json.Unmarshal(w.Body.Bytes(), &word); err != nil {
+		t.Fatalf("failed to unmarshal word: %v", err)
+	}
+	path := "/v1/words/" + strconv.FormatUint(uint64(word.ID), 10)
+
+	// Other users study the shared words without changing them
+	for _, request := range []struct {
+		method, path string
+		body         any
+	}{
+		{http.MethodPost, "/v1/words", map[string]string{"word": "el gato", "translation": "die Katze", "category": "animals"}},
+		{http.MethodPatch, path, map[string]string{"translation": "der Köter"}},
+		{http.MethodDelete, path, nil},
+		{http.MethodDelete, "/v1/media/1", nil},
+		{http.MethodPatch, "/v1/decks/" + strconv.FormatUint(uint64(*word.DeckID), 10), map[string]bool{"reverse_cards": true}},
+	} {
+		if w := doJSON(t, router, request.method, request.path, token, request.body); w.Code != http.StatusForbidden {
+			t.Fatalf("expected 403 on %s %s, got %d", request.method, request.path, w.Code)
+		}
+	}
+	for _, path := range []string{"/v1/words/" + strconv.FormatUint(uint64(word.ID), 10) + "/media", "/v1/import/anki", "/v1/import/csv"} {
+		if w := postPackage(t, router, path, token, []byte("x"), nil); w.Code != http.StatusForbidden {
+			t.Fatalf("expected 403 on POST %s, got %d", path, w.Code)
+		}
+	}
+
+	if w := doJSON(t, router, http.MethodGet, path, token, nil); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 reading a word, got %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodPost, path+"/answer", token, map[string]string{"answer": "der Hund"}); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 answering a word, got %d, body: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestWordAlternativeTranslations(t *testing.T) {
+	router, db := setupAuthTest(t)
+	token := registerAdmin(t, router, db, "ana")
+
+	w := doJSON(t, router, http.MethodPost, "/v1/words", token, map[string]any{
+		"word": "la naranja", "translation": "die Orange", "category": "food",
+		"alternatives": []string{" die Apfelsine ", "die orange", ""},
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 on create, got %d, body: %s", w.Code, w.Body.String())
+	}
+	var created models.Word
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("failed to unmarshal word: %v", err)
+	}
+	if got := created.AcceptedTranslations(); len(got) != 2 || got[1] != "die Apfelsine" {
+		t.Fatalf("expected the primary and one trimmed alternative, got %v", got)
+	}
+
+	// Due cards carry every accepted translation
+	w = doJSON(t, router, http.MethodGet, "/v1/words/daily", token, nil)
+	var userWords []models.UserWord
+	if err := json.Unmarshal(w.Body.Bytes(), &userWords); err != nil {
+		t.Fatalf("failed to unmarshal daily words: %v", err)
+	}
+	if len(userWords) != 1 || len(userWords[0].Word.Translations) != 2 || !userWords[0].Word.Translations[0].IsPrimary {
+		t.Fatalf("expected the translations with the daily word, got %+v", userWords)
+	}
+
+	path := "/v1/words/" + strconv.FormatUint(uint64(created.ID), 10)
+	w = doJSON(t, router, http.MethodPost, path+"/answer", token, map[string]string{"answer": "apfelsine"})
+	var result struct {
+		Verdict  string   `json:"verdict"`
+		Expected string   `json:"expected"`
+		Accepted []string `json:"accepted"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("failed to unmarshal answer: %v", err)
+	}
+	if result.Verdict != "correct" || result.Expected != "die Apfelsine" || len(result.Accepted) != 2 {
+		t.Fatalf("expected the alternative to be accepted, got %+v", result)
+	}
+
+	// Changing the primary translation keeps the alternatives unless they are replaced
+	w = doJSON(t, router, http.MethodPatch, path, token, map[string]string{"translation": "die Navel"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on patch, got %d, body: %s", w.Code, w.Body.String())
+	}
+	w = doJSON(t, router, http.MethodPatch, path, token, map[string]any{"alternatives": []string{"die Orange"}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on patch, got %d, body: %s", w.Code, w.Body.String())
+	}
+	w = doJSON(t, router, http.MethodGet, path, token, nil)
+	var fetched models.Word
+	if err := json.Unmarshal(w.Body.Bytes(), &fetched); err != nil {
+		t.Fatalf("failed to unmarshal word: %v", err)
+	}
+	if got := fetched.AcceptedTranslations(); len(got) != 2 || got[0] != "die Navel" || got[1] != "die Orange" {
+		t.Fatalf("unexpected translations after update: %v", got)
+	}
+}
+
+func TestWordExamplesAndNotes(t *testing.T) {
+	router, db := setupAuthTest(t)
+	token := registerAdmin(t, router, db, "ana")
+
+	w := doJSON(t, router, http.MethodPost, "/v1/words", token, map[string]string{
+		"word": "correr", "translation": "laufen", "category": "verbs",
+		"example_sentence": " Corro cada mañana. ", "example_translation": "Ich laufe jeden Morgen.",
+		"note": "irregular in German", "part_of_speech": "Verb",
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 on create, got %d, body: %s", w.Code, w.Body.String())
+	}
+	var created models.Word
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("failed to unmarshal word: %v", err)
+	}
+	if created.ExampleSentence != "Corro cada mañana." || created.PartOfSpeech != "verb" {
+		t.Fatalf("expected normalized optional fields, got %+v", created)
+	}
+
+	path := "/v1/words/" + strconv.FormatUint(uint64(created.ID), 10)
+	if w := doJSON(t, router, http.MethodPatch, path, token, map[string]string{"note": ""}); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on patch, got %d, body: %s", w.Code, w.Body.String())
+	}
+	if w := doJSON(t, router, http.MethodPatch, path, token, map[string]string{"note": strings.Repeat("a", 1001)}); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a too long note, got %d", w.Code)
+	}
+
+	w = doJSON(t, router, http.MethodGet, "/v1/words/daily", token, nil)
+	var userWords []models.UserWord
+	if err := json.Unmarshal(w.Body.Bytes(), &userWords); err != nil {
+		t.Fatalf("failed to unmarshal daily words: %v", err)
+	}
+	if len(userWords) != 1 {
+		t.Fatalf("expected one due word, got %d", len(userWords))
+	}
+	word := userWords[0].Word
+	if word.ExampleTranslation != "Ich laufe jeden Morgen." || word.Note != "" || word.PartOfSpeech != "verb" {
+		t.Fatalf("expected the example and note with the daily word, got %+v", word)
+	}
+}
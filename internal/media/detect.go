@@ -0,0 +1,45 @@
+package media
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Kinds of media
+const (
+	KindImage = "image"
+	KindAudio = "audio"
+)
+
+// ErrUnsupportedType is returned for content that is neither a supported
+// image nor a supported audio format.
+var ErrUnsupportedType = errors.New("unsupported media type, want a JPEG, PNG, GIF or WebP image or MP3, Ogg or WAV audio")
+
+// Type is a supported media format.
+type Type struct {
+	Kind      string
+	MimeType  string
+	Extension string
+}
+
+// types maps the content types reported by http.DetectContentType to the
+// supported formats.
+var types = map[string]Type{
+	"image/jpeg":      {KindImage, "image/jpeg", ".jpg"},
+	"image/png":       {KindImage, "image/png", ".png"},
+	"image/gif":       {KindImage, "image/gif", ".gif"},
+	"image/webp":      {KindImage, "image/webp", ".webp"},
+	"audio/mpeg":      {KindAudio, "audio/mpeg", ".mp3"},
+	"application/ogg": {KindAudio, "audio/ogg", ".ogg"},
+	"audio/wave":      {KindAudio, "audio/wav", ".wav"},
+}
+
+// Detect sniffs the format of content from its first bytes, ignoring the
+// file name and the type claimed by the client.
+func Detect(content []byte) (Type, error) {
+	t, ok := types[http.DetectContentType(content)]
+	if !ok {
+		return Type{}, ErrUnsupportedType
+	}
+	return t, nil
+}
@@ -0,0 +1,107 @@
+// Package media stores the images and audio attached to words.
+package media
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// ErrInvalidName is returned for names that are not produced by Name.
+var ErrInvalidName = errors.New("invalid media name")
+
+// Store keeps media files by name. Names are content hashes, so a file that
+// exists never changes.
+type Store interface {
+	Exists(name string) (bool, error)
+	Save(name string, content io.Reader) error
+	Open(name string) (io.ReadSeekCloser, error)
+	Delete(name string) error
+}
+
+var namePattern = regexp.MustCompile(`^[0-9a-f]{64}\.[a-z0-9]+$`)
+
+// Name is the name of a file with the given SHA-256 hex digest and extension.
+func Name(hash, extension string) string {
+	return hash + extension
+}
+
+// LocalStore keeps media files in a directory of the local filesystem, in
+// sub-directories named after the first two characters of the hash.
+type LocalStore struct {
+	dir string
+}
+
+func NewLocalStore(dir string) (*LocalStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create media directory: %w", err)
+	}
+	return &LocalStore{dir: dir}, nil
+}
+
+func (s *LocalStore) path(name string) (string, error) {
+	if !namePattern.MatchString(name) {
+		return "", ErrInvalidName
+	}
+	return filepath.Join(s.dir, name[:2], name), nil
+}
+
+func (s *LocalStore) Exists(name string) (bool, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return false, err
+	}
+	_, err = os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Save writes the file to a temporary file first, so that a failed upload
+// never leaves a partial file under its final name.
+func (s *LocalStore) Save(name string, content io.Reader) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *LocalStore) Open(name string) (io.ReadSeekCloser, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+func (s *LocalStore) Delete(name string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+// Package middleware contains the Gin middlewares shared by the API routes.
+package middleware
+
+import (
+	"errors"
+	"learning-cards/internal/models"
+	"learning-cards/internal/services"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	userIDKey = "userID"
+	// SessionCookie carries the access token for browser clients.
+	SessionCookie = "session"
+)
+
+// Authenticate resolves the current user from a bearer token in the
+// Authorization header or from the session cookie and rejects the request
+// when neither holds a valid session token or API key. API keys limited to
+// scopes may only read, and review-only keys may additionally call the routes
+// listed in reviewRoutes (full route paths as registered).
+func Authenticate(auth *services.AuthService, apiKeys *services.APIKeyService, reviewRoutes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := AccessToken(c)
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			return
+		}
+
+		if strings.HasPrefix(token, services.APIKeyPrefix) {
+			key, err := apiKeys.Authenticate(token)
+			if err != nil {
+				abortWithAuthError(c, err)
+				return
+			}
+			if !scopeAllows(key, c.Request.Method, slices.Contains(reviewRoutes, c.FullPath())) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "API key scope does not allow this request"})
+				return
+			}
+			SetUserID(c, key.UserID)
+			c.Next()
+			return
+		}
+
+		user, err := auth.Authenticate(token)
+		if err != nil {
+			abortWithAuthError(c, err)
+			return
+		}
+
+		SetUserID(c, user.ID)
+		c.Next()
+	}
+}
+
+func scopeAllows(key models.APIKey, method string, reviewRoute bool) bool {
+	if len(key.ScopeList()) == 0 {
+		return true
+	}
+	if method == http.MethodGet || method == http.MethodHead {
+		return true
+	}
+	return reviewRoute && key.HasScope(models.ScopeReview)
+}
+
+func abortWithAuthError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrInvalidToken) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to authenticate"})
+}
+
+// AccessToken returns the token sent with the request, if any.
+func AccessToken(c *gin.Context) string {
+	if header := c.GetHeader("Authorization"); header != "" {
+		if token, ok := strings.CutPrefix(header, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+	if cookie, err := c.Cookie(SessionCookie); err == nil {
+		return cookie
+	}
+	return ""
+}
+
+// SetUserID stores the current user on the request context.
+func SetUserID(c *gin.Context, userID uint) {
+	c.Set(userIDKey, userID)
+}
+
+// UserID returns the current user stored by Authenticate.
+func UserID(c *gin.Context) uint {
+	return c.GetUint(userIDKey)
+}
+
+// RequireAdmin rejects requests of users without admin rights. It must run
+// after Authenticate.
+func RequireAdmin(users *services.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		isAdmin, err := users.IsAdmin(UserID(c))
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to authorize"})
+			return
+		}
+		if !isAdmin {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin rights required"})
+			return
+		}
+		c.Next()
+	}
+}
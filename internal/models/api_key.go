@@ -0,0 +1,45 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// API key scopes. A key without scopes has the same access as a login.
+const (
+	ScopeRead   = "read"
+	ScopeReview = "review"
+)
+
+// APIKey lets scripts act for a user without an interactive login. Only a hash
+// of the key is stored; Prefix is kept to tell keys apart in listings.
+type APIKey struct {
+	ID         uint   `gorm:"primary_key"`
+	UserID     uint   `gorm:"not null;index"`
+	Name       string `gorm:"size:255;not null"`
+	Prefix     string `gorm:"size:16;not null"`
+	KeyHash    string `gorm:"size:64;not null;unique" json:"-"`
+	Scopes     string `gorm:"size:255"` // Comma separated, empty means full access
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time `gorm:"DEFAULT:CURRENT_TIMESTAMP"`
+	User       User      `gorm:"foreignKey:UserID" json:"-"`
+}
+
+// ScopeList returns the scopes of the key, empty for full access.
+func (k APIKey) ScopeList() []string {
+	if k.Scopes == "" {
+		return nil
+	}
+	return strings.Split(k.Scopes, ",")
+}
+
+// HasScope reports whether the key was granted the scope.
+func (k APIKey) HasScope(scope string) bool {
+	for _, s := range k.ScopeList() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
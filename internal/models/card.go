@@ -0,0 +1,67 @@
+package models
+
+import "fmt"
+
+// Direction is the side of a word a card asks for. Forward cards show the word
+// and ask for its translation, reverse cards show the translation.
+type Direction string
+
+const (
+	DirectionForward Direction = "forward"
+	DirectionReverse Direction = "reverse"
+)
+
+// ParseDirection parses "forward" or "reverse", an empty string is forward.
+func ParseDirection(s string) (Direction, error) {
+	switch d := Direction(s); d {
+	case "":
+		return DirectionForward, nil
+	case DirectionForward, DirectionReverse:
+		return d, nil
+	}
+	return "", fmt.Errorf("invalid direction %q, want forward or reverse", s)
+}
+
+// CardType is what a card drills. Translation cards ask for the other side of
+// the word, gender cards for the grammatical gender of the side the direction
+// asks for and cloze cards for the word blanked out of its example sentence.
+type CardType string
+
+const (
+	CardTypeTranslation CardType = "translation"
+	CardTypeGender      CardType = "gender"
+	CardTypeCloze       CardType = "cloze"
+)
+
+// ParseCardType parses "translation", "gender" or "cloze", an empty string is translation.
+func ParseCardType(s string) (CardType, error) {
+	switch t := CardType(s); t {
+	case "":
+		return CardTypeTranslation, nil
+	case CardTypeTranslation, CardTypeGender, CardTypeCloze:
+		return t, nil
+	}
+	return "", fmt.Errorf("invalid card type %q, want translation, gender or cloze", s)
+}
+
+// Card identifies one of the cards of a word. Every card of a user has its own
+// scheduling state.
+type Card struct {
+	WordID    uint
+	Type      CardType
+	Direction Direction
+}
+
+// ParseCard builds the card of a word from the card type and direction given
+// by a client, both optional.
+func ParseCard(wordID uint, cardType, direction string) (Card, error) {
+	t, err := ParseCardType(cardType)
+	if err != nil {
+		return Card{}, err
+	}
+	d, err := ParseDirection(direction)
+	if err != nil {
+		return Card{}, err
+	}
+	return Card{WordID: wordID, Type: t, Direction: d}, nil
+}
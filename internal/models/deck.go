@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Deck groups words. Decks can be nested: Path holds the names of all levels
+// joined by "::" (e.g. business::meetings) and Name only the last one. Words
+// keep the deck path in Category for clients that filter by category.
+type Deck struct {
+	ID             uint      `gorm:"primary_key"`
+	ParentID       *uint     `gorm:"index"`
+	Name           string    `gorm:"size:255;not null"`
+	Path           string    `gorm:"size:255;not null;default:''"`
+	Slug           string    `gorm:"size:255;not null;unique"`
+	Description    string    `gorm:"type:text"`
+	SourceLanguage string    `gorm:"size:16"`
+	TargetLanguage string    `gorm:"size:16"`
+	ReverseCards   bool      `gorm:"not null;default:false"` // add a translation to word card for every word
+	GenderCards    bool      `gorm:"not null;default:false"` // add a gender card for every noun with an article
+	ClozeCards     bool      `gorm:"not null;default:false"` // add a cloze card for every word with an example sentence
+	CreatedAt      time.Time `gorm:"DEFAULT:CURRENT_TIMESTAMP"`
+}
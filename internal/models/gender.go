@@ -0,0 +1,10 @@
+package models
+
+// Gender is the grammatical gender of a noun.
+type Gender string
+
+const (
+	GenderMasculine Gender = "masculine"
+	GenderFeminine  Gender = "feminine"
+	GenderNeuter    Gender = "neuter"
+)
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Grade rates how well a word was recalled, from complete failure to effortless.
+type Grade int
+
+const (
+	GradeAgain Grade = iota + 1
+	GradeHard
+	GradeGood
+	GradeEasy
+)
+
+var gradeNames = map[Grade]string{
+	GradeAgain: "again",
+	GradeHard:  "hard",
+	GradeGood:  "good",
+	GradeEasy:  "easy",
+}
+
+// GradeFromLearned maps the legacy learned flag onto a grade.
+func GradeFromLearned(learned bool) Grade {
+	if learned {
+		return GradeGood
+	}
+	return GradeAgain
+}
+
+// ParseGrade parses one of "again", "hard", "good" or "easy".
+func ParseGrade(s string) (Grade, error) {
+	for grade, name := range gradeNames {
+		if name == s {
+			return grade, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid grade %q, want one of again, hard, good, easy", s)
+}
+
+func (g Grade) Valid() bool {
+	_, ok := gradeNames[g]
+	return ok
+}
+
+// Passed reports whether the word was recalled at all.
+func (g Grade) Passed() bool {
+	return g > GradeAgain
+}
+
+func (g Grade) String() string {
+	if name, ok := gradeNames[g]; ok {
+		return name
+	}
+	return fmt.Sprintf("Grade(%d)", int(g))
+}
+
+func (g Grade) MarshalText() ([]byte, error) {
+	if !g.Valid() {
+		return nil, fmt.Errorf("invalid grade %d", int(g))
+	}
+	return []byte(g.String()), nil
+}
+
+func (g *Grade) UnmarshalText(text []byte) error {
+	grade, err := ParseGrade(string(text))
+	if err != nil {
+		return err
+	}
+	*g = grade
+	return nil
+}
+
+// UnmarshalJSON accepts the name of a grade or its number, from 1 (again) to 4 (easy)
+func (g *Grade) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var number int
+	if err := json.Unmarshal(data, &number); err == nil {
+		if !Grade(number).Valid() {
+			return fmt.Errorf("invalid grade %d, want 1 (again) to 4 (easy)", number)
+		}
+		*g = Grade(number)
+		return nil
+	}
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return fmt.Errorf("invalid grade %s, want one of again, hard, good, easy or 1 to 4", data)
+	}
+	return g.UnmarshalText([]byte(name))
+}
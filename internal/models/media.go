@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Media is an image or audio file attached to a word. The file is stored once
+// per content hash under Name, however many words it is attached to.
+type Media struct {
+	ID        uint      `gorm:"primary_key"`
+	WordID    uint      `gorm:"not null;uniqueIndex:idx_media_word_hash"`
+	Kind      string    `gorm:"size:16;not null"` // image or audio
+	MimeType  string    `gorm:"size:64;not null"`
+	Hash      string    `gorm:"size:64;not null;index;uniqueIndex:idx_media_word_hash"` // SHA-256 of the content
+	Name      string    `gorm:"size:80;not null"`
+	Size      int64     `gorm:"not null"`
+	Filename  string    `gorm:"size:255"` // as uploaded
+	CreatedAt time.Time `gorm:"DEFAULT:CURRENT_TIMESTAMP"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// ReviewLog records a single answer together with the scheduling state before
+// and after it, so the history of a user word is never lost.
+type ReviewLog struct {
+	ID                   uint      `gorm:"primary_key"`
+	UserWordID           uint      `gorm:"not null;index"`
+	ReviewedAt           time.Time `gorm:"not null;index"`
+	Grade                Grade     `gorm:"not null"`
+	PreviousBox          uint
+	PreviousIntervalDays uint
+	NewBox               uint
+	NewIntervalDays      uint
+	ElapsedDays          float64 // Days since the previous review of the user word
+	ResponseTimeMs       uint
+}
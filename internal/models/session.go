@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Session is a login of a user. Only hashes of the access and refresh tokens
+// are stored, the tokens themselves are handed to the client once.
+type Session struct {
+	ID               uint      `gorm:"primary_key"`
+	UserID           uint      `gorm:"not null;index"`
+	TokenHash        string    `gorm:"size:64;not null;unique"`
+	RefreshTokenHash string    `gorm:"size:64;not null;unique"`
+	ExpiresAt        time.Time `gorm:"not null"`
+	RefreshExpiresAt time.Time `gorm:"not null;index"`
+	CreatedAt        time.Time `gorm:"DEFAULT:CURRENT_TIMESTAMP"`
+	User             User      `gorm:"foreignKey:UserID"`
+}
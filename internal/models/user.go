@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// DefaultUsername is the learner that owns the progress recorded before
+// accounts existed.
+const DefaultUsername = "default"
+
+type User struct {
+	ID       uint   `gorm:"primary_key"`
+	Username string `gorm:"size:255;not null;unique"`
+	// Users created before authentication existed have no password until an operator sets one
+	PasswordHash string    `gorm:"size:255" json:"-"`
+	IsAdmin      bool      `gorm:"not null;default:false"` // may change the shared words and decks and back up the collection
+	CreatedAt    time.Time `gorm:"DEFAULT:CURRENT_TIMESTAMP"`
+}
@@ -4,11 +4,22 @@ import "time"
 
 type UserWord struct {
 	ID                uint      `gorm:"primary_key,auto_increment"`
-	WordID            uint      `gorm:"not null;index;unique"`
+	UserID            uint      `gorm:"not null;default:0;uniqueIndex:idx_user_words_unique_card"`
+	WordID            uint      `gorm:"not null;index;uniqueIndex:idx_user_words_unique_card"`
+	CardType          CardType  `gorm:"size:16;not null;default:translation;uniqueIndex:idx_user_words_unique_card"`
+	Direction         Direction `gorm:"size:16;not null;default:forward;uniqueIndex:idx_user_words_unique_card"`
 	BoxNumber         uint      `gorm:"default:1"`
 	LastReview        time.Time `gorm:"DEFAULT:CURRENT_TIMESTAMP"`
 	NextReview        time.Time `gorm:"DEFAULT:CURRENT_TIMESTAMP"`
 	CorrectAttempts   uint      `gorm:"default:0"`
 	IncorrectAttempts uint      `gorm:"default:0"`
-	Word              Word      `gorm:"foreignKey:WordID"` // Specify the foreign key relationship
+	// Scheduling state used by algorithms other than the Leitner boxes
+	EaseFactor   float64 `gorm:"default:2.5"`
+	Repetitions  uint    `gorm:"default:0"`
+	IntervalDays uint    `gorm:"default:0"`
+	Stability    float64 `gorm:"default:0"`
+	Difficulty   float64 `gorm:"default:0"`
+	Word         Word    `gorm:"foreignKey:WordID"` // Specify the foreign key relationship
+	// Cloze is the example sentence with the word blanked out, filled for cloze cards only
+	Cloze string `gorm:"-"`
 }
@@ -1,13 +1,62 @@
 package models
 
 import (
+	"strings"
 	"time"
 )
 
 type Word struct {
-	ID          uint      `gorm:"primary_key"`
-	Word        string    `gorm:"size:255"`
-	Translation string    `gorm:"size:255"`
-	Category    string    `gorm:"size:255"`
-	CreatedAt   time.Time `gorm:"DEFAULT:CURRENT_TIMESTAMP"`
+	ID                 uint   `gorm:"primary_key"`
+	Word               string `gorm:"size:255"`
+	Translation        string `gorm:"size:255"`
+	Category           string `gorm:"size:255"`
+	Gender             Gender `gorm:"size:16;not null;default:''"` // parsed from the article of Word
+	TranslationGender  Gender `gorm:"size:16;not null;default:''"` // parsed from the article of Translation
+	ExampleSentence    string `gorm:"type:text"`                   // a sentence using Word
+	ExampleTranslation string `gorm:"type:text"`                   // the translation of ExampleSentence
+	Note               string `gorm:"type:text"`                   // free text shown with the card
+	PartOfSpeech       string `gorm:"size:32"`                     // e.g. noun, verb, adjective
+	DeckID             *uint  `gorm:"index"`
+	// Deck is only loaded for the foreign key, words leave their deck when it is deleted
+	Deck *Deck `gorm:"foreignKey:DeckID;constraint:OnDelete:SET NULL" json:"-"`
+	// SourceFile is the data CSV the word is seeded from, relative to the data directory, and SourceKey
+	// identifies the word within it, so that it is kept when its text changes
+	SourceFile string `gorm:"size:255;index;uniqueIndex:idx_words_source_key,where:source_key <> ''"`
+	SourceKey  string `gorm:"size:255;uniqueIndex:idx_words_source_key"`
+	// RetiredAt is set when the word was removed from SourceFile, its cards are no longer studied
+	RetiredAt    *time.Time        `gorm:"index"`
+	CreatedAt    time.Time         `gorm:"DEFAULT:CURRENT_TIMESTAMP"`
+	Translations []WordTranslation `gorm:"foreignKey:WordID"` // all accepted translations, primary first
+	Media        []Media           `gorm:"foreignKey:WordID"`
+}
+
+// SetTranslations sets the primary translation and the other accepted ones.
+// Empty alternatives and repetitions ignoring case are dropped.
+func (w *Word) SetTranslations(primary string, alternatives []string) {
+	w.Translation = primary
+	w.Translations = []WordTranslation{{Translation: primary, IsPrimary: true}}
+	seen := map[string]struct{}{strings.ToLower(primary): {}}
+	for _, alternative := range alternatives {
+		if _, ok := seen[strings.ToLower(alternative)]; ok || alternative == "" {
+			continue
+		}
+		seen[strings.ToLower(alternative)] = struct{}{}
+		w.Translations = append(w.Translations, WordTranslation{Translation: alternative})
+	}
+}
+
+// Alternatives returns the accepted translations other than the primary one.
+func (w Word) Alternatives() []string {
+	var alternatives []string
+	for _, translation := range w.Translations {
+		if !translation.IsPrimary {
+			alternatives = append(alternatives, translation.Translation)
+		}
+	}
+	return alternatives
+}
+
+// AcceptedTranslations returns the primary translation followed by the alternatives.
+func (w Word) AcceptedTranslations() []string {
+	return append([]string{w.Translation}, w.Alternatives()...)
 }
@@ -0,0 +1,10 @@
+package models
+
+// WordTranslation is an accepted translation of a word. The primary one is
+// also kept in Word.Translation.
+type WordTranslation struct {
+	ID          uint   `gorm:"primary_key"`
+	WordID      uint   `gorm:"not null;index"`
+	Translation string `gorm:"size:255;not null"`
+	IsPrimary   bool   `gorm:"not null;default:false"`
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"learning-cards/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+func (ar *APIKeyRepository) CreateAPIKey(key *models.APIKey) error {
+	return ar.db.Create(key).Error
+}
+
+// GetAPIKeys Get the keys of a user, including revoked ones
+func (ar *APIKeyRepository) GetAPIKeys(userID uint) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	if err := ar.db.Where("user_id = ?", userID).Order("id").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// GetActiveAPIKeyByHash Get a key that has not been revoked together with its user
+func (ar *APIKeyRepository) GetActiveAPIKeyByHash(keyHash string) (models.APIKey, error) {
+	var key models.APIKey
+	err := ar.db.Preload("User").
+		Where("key_hash = ? AND revoked_at IS NULL", keyHash).
+		First(&key).Error
+	return key, err
+}
+
+// RevokeAPIKey Revoke a key of the user, returns gorm.ErrRecordNotFound for unknown or revoked keys
+func (ar *APIKeyRepository) RevokeAPIKey(userID, keyID uint, now time.Time) error {
+	result := ar.db.Model(&models.APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", keyID, userID).
+		Update("revoked_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (ar *APIKeyRepository) TouchAPIKey(keyID uint, now time.Time) error {
+	return ar.db.Model(&models.APIKey{}).Where("id = ?", keyID).Update("last_used_at", now).Error
+}
@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"learning-cards/internal/backup"
+	"learning-cards/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// backupBatchSize is the number of rows read or written at once
+const backupBatchSize = 500
+
+// restoredTables are the tables a restore replaces, in the order they are filled
+var restoredTables = []string{"users", "decks", "words", "word_translations", "media", "user_words", "review_logs"}
+
+// errDryRun rolls back the transaction of a dry run
+var errDryRun = errors.New("dry run")
+
+// backupWriter receives the records of a backup, see backup.Writer and backup.Snapshot
+type backupWriter interface {
+	Write(record any) error
+}
+
+type BackupRepository struct {
+	db *gorm.DB
+}
+
+func NewBackupRepository(db *gorm.DB) *BackupRepository {
+	return &BackupRepository{db: db}
+}
+
+// WriteBackup Write every user, deck, word, user word and review log in id order, batch by batch. All tables are read
+// in one read-only transaction, so that the records of a backup taken while the collection changes refer to each other.
+func (br *BackupRepository) WriteBackup(w backupWriter) error {
+	return br.db.Transaction(func(tx *gorm.DB) error {
+		return writeBackup(tx, w)
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+}
+
+// Restore Replace the collection with a backup within a single transaction and
+// report what changed. A dry run reports the changes and rolls them back.
+// Users missing from the backup are deleted together with their sessions and API keys.
+func (br *BackupRepository) Restore(snapshot *backup.Snapshot, dryRun bool) (backup.Changes, error) {
+	var changes backup.Changes
+	err := br.db.Transaction(func(tx *gorm.DB) error {
+		current := &backup.Snapshot{}
+		if err := writeBackup(tx, current); err != nil {
+			return err
+		}
+		changes = backup.Diff(current, snapshot)
+
+		if err := restoreUsers(tx, snapshot.Users); err != nil {
+			return err
+		}
+		// Everything else is replaced, children first
+		for _, model := range []any{&models.ReviewLog{}, &models.UserWord{}, &models.Media{}, &models.WordTranslation{}, &models.Word{}, &models.Deck{}} {
+			if err := tx.Where("1 = 1").Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if err := restoreRecords(tx, snapshot.Decks, backup.Deck.Model); err != nil {
+			return err
+		}
+		if err := restoreWords(tx, snapshot.Words); err != nil {
+			return err
+		}
+		if err := restoreRecords(tx, snapshot.UserWords, backup.UserWord.Model); err != nil {
+			return err
+		}
+		if err := restoreRecords(tx, snapshot.ReviewLogs, backup.ReviewLog.Model); err != nil {
+			return err
+		}
+		if err := resetSequences(tx); err != nil {
+			return err
+		}
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if errors.Is(err, errDryRun) {
+		err = nil
+	}
+	return changes, err
+}
+
+func writeBackup(db *gorm.DB, w backupWriter) error {
+	if err := writeRecords(db, w, backup.FromUser); err != nil {
+		return err
+	}
+	if err := writeRecords(db, w, backup.FromDeck); err != nil {
+		return err
+	}
+	words := db.Preload("Translations", orderTranslations).Preload("Media", orderMedia)
+	if err := writeRecords(words, w, backup.FromWord); err != nil {
+		return err
+	}
+	if err := writeRecords(db, w, backup.FromUserWord); err != nil {
+		return err
+	}
+	return writeRecords(db, w, backup.FromReviewLog)
+}
+
+// writeRecords reads a table in batches of rows in id order and writes them as records
+func writeRecords[M any, R any](query *gorm.DB, w backupWriter, convert func(M) R) error {
+	var rows []M
+	var writeErr error
+	result := query.FindInBatches(&rows, backupBatchSize, func(tx *gorm.DB, batch int) error {
+		for _, row := range rows {
+			if writeErr = w.Write(convert(row)); writeErr != nil {
+				return writeErr
+			}
+		}
+		return nil
+	})
+	if writeErr != nil {
+		return writeErr
+	}
+	return result.Error
+}
+
+// restoreRecords inserts records in batches, keeping their ids
+func restoreRecords[R any, M any](tx *gorm.DB, records []R, convert func(R) M) error {
+	if len(records) == 0 {
+		return nil
+	}
+	rows := make([]M, len(records))
+	for i, record := range records {
+		rows[i] = convert(record)
+	}
+	return insertAll(tx).CreateInBatches(rows, backupBatchSize).Error
+}
+
+// insertAll inserts every column, zero values included, where gorm would
+// otherwise leave columns with a default to the database
+func insertAll(tx *gorm.DB) *gorm.DB {
+	return tx.Select("*").Omit(clause.Associations)
+}
+
+// restoreUsers updates the users of the backup in place, so that the sessions of
+// users that are kept remain valid, and deletes the others
+func restoreUsers(tx *gorm.DB, users []backup.User) error {
+	ids := make([]uint, len(users))
+	for i, user := range users {
+		ids[i] = user.ID
+	}
+	removed := tx.Model(&models.User{}).Select("id")
+	if len(ids) > 0 {
+		removed = removed.Where("id NOT IN ?", ids)
+	}
+	for _, model := range []any{&models.Session{}, &models.APIKey{}} {
+		if err := tx.Where("user_id IN (?)", removed).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	deleteUsers := tx.Where("1 = 1")
+	if len(ids) > 0 {
+		deleteUsers = tx.Where("id NOT IN ?", ids)
+	}
+	if err := deleteUsers.Delete(&models.User{}).Error; err != nil {
+		return err
+	}
+	if len(users) == 0 {
+		return nil
+	}
+	rows := make([]models.User, len(users))
+	for i, user := range users {
+		rows[i] = user.Model()
+	}
+	return insertAll(tx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		UpdateAll: true,
+	}).CreateInBatches(rows, backupBatchSize).Error
+}
+
+// restoreWords inserts the words, then their translations and media
+func restoreWords(tx *gorm.DB, records []backup.Word) error {
+	var translations []models.WordTranslation
+	var media []models.Media
+	for start := 0; start < len(records); start += backupBatchSize {
+		batch := records[start:min(start+backupBatchSize, len(records))]
+		words := make([]models.Word, len(batch))
+		for i, record := range batch {
+			word := record.Model()
+			translations = append(translations, word.Translations...)
+			media = append(media, word.Media...)
+			word.Translations, word.Media = nil, nil
+			words[i] = word
+		}
+		if err := insertAll(tx).Create(&words).Error; err != nil {
+			return err
+		}
+	}
+	if len(translations) > 0 {
+		if err := insertAll(tx).CreateInBatches(translations, backupBatchSize).Error; err != nil {
+			return err
+		}
+	}
+	if len(media) > 0 {
+		return insertAll(tx).CreateInBatches(media, backupBatchSize).Error
+	}
+	return nil
+}
+
+// resetSequences moves the id sequences of postgres past the restored ids,
+// sqlite picks the next id from the table itself
+func resetSequences(tx *gorm.DB) error {
+	if tx.Dialector.Name() != "postgres" {
+		return nil
+	}
+	for _, table := range restoredTables {
+		query := fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%[1]s', 'id'), COALESCE((SELECT MAX(id) FROM %[1]s), 0) + 1, false)", table)
+		if err := tx.Exec(query).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"learning-cards/internal/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// cardKind is a kind of card together with the condition a word and its deck
+// must meet to have one. Conditions refer to the words and decks tables.
+type cardKind struct {
+	cardType  models.CardType
+	direction models.Direction
+	condition string
+}
+
+var cardKinds = []cardKind{
+	{models.CardTypeTranslation, models.DirectionForward, ""},
+	{models.CardTypeTranslation, models.DirectionReverse, "decks.reverse_cards"},
+	{models.CardTypeGender, models.DirectionForward, "decks.gender_cards AND words.translation_gender <> ''"},
+	{models.CardTypeGender, models.DirectionReverse, "decks.gender_cards AND decks.reverse_cards AND words.gender <> ''"},
+	{models.CardTypeCloze, models.DirectionForward, "decks.cloze_cards AND words.example_sentence <> ''"},
+}
+
+// whereCard filters user words on a card of the user
+func whereCard(query *gorm.DB, userID uint, card models.Card) *gorm.DB {
+	return query.Where("user_words.user_id = ? AND user_words.word_id = ? AND user_words.card_type = ? AND user_words.direction = ?",
+		userID, card.WordID, card.Type, card.Direction)
+}
+
+// whereActiveCard filters user words on a card of the user that is studied,
+// so that cards of retired words or of kinds their deck turned off are not found
+func whereActiveCard(query *gorm.DB, userID uint, card models.Card) *gorm.DB {
+	return activeCards(joinDecks(whereCard(query, userID, card)))
+}
+
+// activeWordCondition matches the words that have not been retired from their data file
+const activeWordCondition = "words.retired_at IS NULL"
+
+// activeCardCondition matches the user words that are enabled by the settings
+// of their deck. Cards of a kind a deck turned off and cards of retired words
+// keep their state but are not studied.
+func activeCardCondition() (string, []interface{}) {
+	conditions := make([]string, 0, len(cardKinds))
+	vars := make([]interface{}, 0, 2*len(cardKinds))
+	for _, kind := range cardKinds {
+		condition := "user_words.card_type = ? AND user_words.direction = ?"
+		if kind.condition != "" {
+			condition += " AND " + kind.condition
+		}
+		conditions = append(conditions, "("+condition+")")
+		vars = append(vars, kind.cardType, kind.direction)
+	}
+	return activeWordCondition + " AND (" + strings.Join(conditions, " OR ") + ")", vars
+}
+
+// activeCards keeps the active cards of a query joined with words and decks
+func activeCards(query *gorm.DB) *gorm.DB {
+	condition, vars := activeCardCondition()
+	return query.Where(condition, vars...)
+}
+
+// createCards inserts every enabled card of the words matching the condition
+// for every user that does not have it yet, with one INSERT ... SELECT per
+// kind of card. The condition may refer to the users, words and decks tables.
+// NOT EXISTS skips the cards users already have without using up ids, ON
+// CONFLICT the ones inserted concurrently.
+func createCards(tx *gorm.DB, now time.Time, condition string, args ...interface{}) error {
+	for _, kind := range cardKinds {
+		where := "(" + condition + ") AND " + activeWordCondition
+		if kind.condition != "" {
+			where += " AND " + kind.condition
+		}
+		vars := append([]interface{}{kind.cardType, kind.direction, now, now}, args...)
+		vars = append(vars, kind.cardType, kind.direction)
+		if err := tx.Exec(`INSERT INTO user_words (user_id, word_id, card_type, direction, box_number, last_review, next_review)
+			SELECT users.id, words.id, ?, ?, 1, ?, ?
+			FROM users CROSS JOIN words
+			LEFT JOIN decks ON words.deck_id = decks.id
+			WHERE `+where+` AND NOT EXISTS (
+				SELECT 1 FROM user_words existing
+				WHERE existing.user_id = users.id AND existing.word_id = words.id
+					AND existing.card_type = ? AND existing.direction = ?)
+			ON CONFLICT (user_id, word_id, card_type, direction) DO NOTHING`,
+			vars...).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
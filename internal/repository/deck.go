@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"errors"
+	"learning-cards/config"
+	"learning-cards/internal/models"
+	"learning-cards/internal/utils"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrEmptyDeckName = errors.New("deck name must not be empty")
+
+// DeckSummary is a deck with the card counts of one user.
+type DeckSummary struct {
+	models.Deck
+	TotalCards int64
+	DueCards   int64
+	NewCards   int64
+}
+
+type DeckRepository struct {
+	db  *gorm.DB
+	cfg config.DeckConfig
+}
+
+func NewDeckRepository(db *gorm.DB, cfg config.DeckConfig) *DeckRepository {
+	return &DeckRepository{db: db, cfg: cfg}
+}
+
+// WithTx returns a copy of the repository that runs its queries in tx
+func (dr *DeckRepository) WithTx(tx *gorm.DB) *DeckRepository {
+	return &DeckRepository{db: tx, cfg: dr.cfg}
+}
+
+// GetDeckSummaries Get all decks with the number of cards, due cards and never reviewed cards of the user.
+// The counts of a deck include the cards of all its sub-decks.
+func (dr *DeckRepository) GetDeckSummaries(userID uint, now time.Time) ([]DeckSummary, error) {
+	var summaries []DeckSummary
+	activeCondition, activeVars := activeCardCondition()
+	err := dr.db.Model(&models.Deck{}).
+		Select(`decks.*,
+			COUNT(user_words.id) AS total_cards,
+			COALESCE(SUM(CASE WHEN user_words.next_review <= ? THEN 1 ELSE 0 END), 0) AS due_cards,
+			COALESCE(SUM(CASE WHEN user_words.correct_attempts + user_words.incorrect_attempts = 0 THEN 1 ELSE 0 END), 0) AS new_cards`, now).
+		Joins("LEFT JOIN words ON words.deck_id = decks.id").
+		Joins("LEFT JOIN user_words ON user_words.word_id = words.id AND user_words.user_id = ? AND "+activeCondition,
+			append([]interface{}{userID}, activeVars...)...).
+		Group("decks.id").
+		Order("decks.slug").
+		Scan(&summaries).Error
+	if err != nil {
+		return nil, err
+	}
+	rollUpCounts(summaries)
+	return summaries, nil
+}
+
+// rollUpCounts adds the counts of every deck to all of its ancestors
+func rollUpCounts(summaries []DeckSummary) {
+	index := make(map[uint]int, len(summaries))
+	for i, summary := range summaries {
+		index[summary.ID] = i
+	}
+	own := make([]DeckSummary, len(summaries))
+	copy(own, summaries)
+
+	for i := range own {
+		for parentID := own[i].ParentID; parentID != nil; {
+			p, ok := index[*parentID]
+			if !ok {
+				break
+			}
+			summaries[p].TotalCards += own[i].TotalCards
+			summaries[p].DueCards += own[i].DueCards
+			summaries[p].NewCards += own[i].NewCards
+			parentID = summaries[p].ParentID
+		}
+	}
+}
+
+func (dr *DeckRepository) GetDeck(id uint) (models.Deck, error) {
+	var deck models.Deck
+	err := dr.db.First(&deck, id).Error
+	return deck, err
+}
+
+// EnsureDeck Get the deck matching the path (e.g. business::meetings) by slug,
+// creating it and its parents if they do not exist yet
+func (dr *DeckRepository) EnsureDeck(path string) (models.Deck, error) {
+	return ensureDeck(dr.db, dr.cfg, path)
+}
+
+// UpdateDeck Save the editable fields of a deck. Turning on reverse, gender or
+// cloze cards adds the new cards for every user and word of the deck right away.
+func (dr *DeckRepository) UpdateDeck(deck *models.Deck) error {
+	return dr.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(deck).
+			Select("Description", "SourceLanguage", "TargetLanguage", "ReverseCards", "GenderCards", "ClozeCards").
+			Updates(deck).Error; err != nil {
+			return err
+		}
+		return createCards(tx, time.Now(), "words.deck_id = ?", deck.ID)
+	})
+}
+
+// ensureDeck returns the deck at the given path, creating it and any missing
+// parent decks.
+func ensureDeck(db *gorm.DB, cfg config.DeckConfig, path string) (models.Deck, error) {
+	segments := utils.SplitDeckPath(path)
+	if len(segments) == 0 {
+		return models.Deck{}, ErrEmptyDeckName
+	}
+
+	var deck models.Deck
+	var parentID *uint
+	for i, segment := range segments {
+		if utils.Slugify(segment) == "" {
+			return models.Deck{}, ErrEmptyDeckName
+		}
+		level := strings.Join(segments[:i+1], utils.DeckPathSeparator)
+		var err error
+		if deck, err = ensureDeckLevel(db, cfg, segment, level, parentID); err != nil {
+			return models.Deck{}, err
+		}
+		parentID = &deck.ID
+	}
+	return deck, nil
+}
+
+func ensureDeckLevel(db *gorm.DB, cfg config.DeckConfig, name, path string, parentID *uint) (models.Deck, error) {
+	slug := utils.SlugifyPath(path)
+
+	var deck models.Deck
+	err := db.Where("slug = ?", slug).First(&deck).Error
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return deck, err
+	}
+
+	deck = models.Deck{
+		ParentID:       parentID,
+		Name:           name,
+		Path:           path,
+		Slug:           slug,
+		SourceLanguage: cfg.SourceLanguage,
+		TargetLanguage: cfg.TargetLanguage,
+		CreatedAt:      time.Now(),
+	}
+	// Another request may have created the deck in the meantime. Skipping the conflict instead of
+	// failing keeps a surrounding transaction usable, Postgres aborts it on a failed statement.
+	result := db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "slug"}}, DoNothing: true}).Create(&deck)
+	if result.Error != nil {
+		return models.Deck{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		var existing models.Deck
+		err := db.Where("slug = ?", slug).First(&existing).Error
+		return existing, err
+	}
+	return deck, nil
+}
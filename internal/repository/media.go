@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"learning-cards/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type MediaRepository struct {
+	db *gorm.DB
+}
+
+func NewMediaRepository(db *gorm.DB) *MediaRepository {
+	return &MediaRepository{db: db}
+}
+
+// GetWordMedia Get the media attached to a word, oldest first
+func (mr *MediaRepository) GetWordMedia(wordID uint) ([]models.Media, error) {
+	var media []models.Media
+	err := mr.db.Where("word_id = ?", wordID).Order("id").Find(&media).Error
+	return media, err
+}
+
+func (mr *MediaRepository) GetMedia(id uint) (models.Media, error) {
+	var media models.Media
+	err := mr.db.First(&media, id).Error
+	return media, err
+}
+
+// GetMediaByName Get any media stored under the name, to know its type
+func (mr *MediaRepository) GetMediaByName(name string) (models.Media, error) {
+	var media models.Media
+	err := mr.db.Where("name = ?", name).First(&media).Error
+	return media, err
+}
+
+// FindWordMedia Get the media of a word with the given content hash
+func (mr *MediaRepository) FindWordMedia(wordID uint, hash string) (models.Media, error) {
+	var media models.Media
+	err := mr.db.Where("word_id = ? AND hash = ?", wordID, hash).First(&media).Error
+	return media, err
+}
+
+func (mr *MediaRepository) CreateMedia(media *models.Media) error {
+	return mr.db.Create(media).Error
+}
+
+// DeleteMedia Delete a media and report whether other media still use its file
+func (mr *MediaRepository) DeleteMedia(media models.Media) (bool, error) {
+	var remaining int64
+	err := mr.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&media).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Media{}).Where("hash = ?", media.Hash).Count(&remaining).Error
+	})
+	return remaining > 0, err
+}
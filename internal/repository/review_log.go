@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"learning-cards/internal/models"
+)
+
+// GetReviewHistory Get the review log of a card, oldest answer first
+func (ur *UserWordRepository) GetReviewHistory(userID uint, card models.Card) ([]models.ReviewLog, error) {
+	var userWord models.UserWord
+	if err := whereCard(ur.db, userID, card).First(&userWord).Error; err != nil {
+		return nil, err
+	}
+
+	var reviewLogs []models.ReviewLog
+	if err := ur.db.Where("user_word_id = ?", userWord.ID).
+		Order("reviewed_at, id").
+		Find(&reviewLogs).Error; err != nil {
+		return nil, err
+	}
+	return reviewLogs, nil
+}
+
+// GetCategoryReviewLogs Get the logged answers of the user, of every word or only of those
+// in a category and its sub-decks, grouped by user word in chronological order
+func (ur *UserWordRepository) GetCategoryReviewLogs(userID uint, category string) ([]models.ReviewLog, error) {
+	var reviewLogs []models.ReviewLog
+	query := joinDecks(ur.db.Select("review_logs.*").
+		Joins("INNER JOIN user_words ON review_logs.user_word_id = user_words.id")).
+		Where("user_words.user_id = ?", userID)
+	if category != "" {
+		query = whereCategory(query, category, true)
+	}
+	if err := query.Order("review_logs.user_word_id, review_logs.reviewed_at, review_logs.id").
+		Find(&reviewLogs).Error; err != nil {
+		return nil, err
+	}
+	return reviewLogs, nil
+}
+
+// GetAllReviewLogs Get every logged answer grouped by user word in chronological order
+func (ur *UserWordRepository) GetAllReviewLogs() ([]models.ReviewLog, error) {
+	var reviewLogs []models.ReviewLog
+	if err := ur.db.Order("user_word_id, reviewed_at, id").Find(&reviewLogs).Error; err != nil {
+		return nil, err
+	}
+	return reviewLogs, nil
+}
@@ -0,0 +1,276 @@
+package repository
+
+import (
+	"errors"
+	"learning-cards/config"
+	"learning-cards/internal/models"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SeedChanges counts what syncing the words of a data file changed. Skipped
+// words already exist without belonging to the file, or appear in it twice.
+type SeedChanges struct {
+	Inserted int
+	Updated  int
+	Retired  int
+	Skipped  int
+}
+
+// Add adds the counts of other to c
+func (c *SeedChanges) Add(other SeedChanges) {
+	c.Inserted += other.Inserted
+	c.Updated += other.Updated
+	c.Retired += other.Retired
+	c.Skipped += other.Skipped
+}
+
+type SeedRepository struct {
+	db  *gorm.DB
+	cfg config.DeckConfig
+}
+
+func NewSeedRepository(db *gorm.DB, cfg config.DeckConfig) *SeedRepository {
+	return &SeedRepository{db: db, cfg: cfg}
+}
+
+// GetSources Get the data files that words have been seeded from
+func (sr *SeedRepository) GetSources() ([]string, error) {
+	var sources []string
+	err := sr.db.Model(&models.Word{}).Distinct("source_file").
+		Where("source_file <> ''").Order("source_file").Pluck("source_file", &sources).Error
+	return sources, err
+}
+
+// seedBatchSize bounds the rows of a batch insert and the values of an IN list, below the parameter limits
+const seedBatchSize = 1000
+
+// SyncSource Bring the words seeded from a data file in line with the words it holds now, in one transaction.
+// Words are matched by their SourceKey. New words are inserted with cards for every user, changed ones are updated
+// and the ones no longer in the file are retired, keeping their cards and history. A retired word that is added
+// back is studied again, unless a word with the same text was added since. A word with the same text that does not belong to any file, like words seeded before
+// files were tracked, is taken over by the file.
+// The number of queries depends on the number of changed words, not on the size of the file.
+func (sr *SeedRepository) SyncSource(source string, words []models.Word) (SeedChanges, error) {
+	var changes SeedChanges
+	err := sr.db.Transaction(func(tx *gorm.DB) error {
+		changes = SeedChanges{}
+		var existing []models.Word
+		if err := tx.Preload("Translations", orderTranslations).Where("source_file = ?", source).Find(&existing).Error; err != nil {
+			return err
+		}
+		byKey := make(map[string]*models.Word, len(existing))
+		for i := range existing {
+			byKey[seedKey(existing[i])] = &existing[i]
+		}
+
+		// Words are matched by key first, so that words whose text changed keep their progress
+		claimed := make(map[uint]bool, len(words))
+		matches := make([]*models.Word, len(words))
+		var lookups []string
+		for i, word := range words {
+			if current, ok := byKey[seedKey(word)]; ok && !claimed[current.ID] {
+				claimed[current.ID] = true
+				matches[i] = current
+			}
+			// A retired word may have lost its text to a word added since
+			if matches[i] == nil || matches[i].RetiredAt != nil || !strings.EqualFold(matches[i].Word, word.Word) {
+				lookups = append(lookups, strings.ToLower(word.Word))
+			}
+		}
+		byText, err := wordsByText(tx, lookups)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		decks := make(map[string]models.Deck)
+		taken := make(map[string]bool, len(words))
+		var inserts, updates []models.Word
+		var adopted []uint
+		keys := make(map[uint]string)
+		for i, word := range words {
+			key := seedKey(word)
+			text := strings.ToLower(word.Word)
+			current := matches[i]
+			if current == nil {
+				if _, ok := byKey[key]; ok {
+					// The key appears twice in the file
+					changes.Skipped++
+					continue
+				}
+			}
+			if taken[text] {
+				// An earlier line of the file has the same text
+				changes.Skipped++
+				continue
+			}
+
+			// The text may only be taken by the word itself, or by a word it can take over: one that does not
+			// belong to any file, or one of this file that no other line matched. A retired word only gives up its
+			// text to a word that is not retired.
+			other, ok := byText[text]
+			if ok && current != nil && current.RetiredAt != nil && other.RetiredAt != nil {
+				ok = false
+			}
+			if ok && (current == nil || other.ID != current.ID) {
+				if current != nil || !(other.SourceFile == "" || other.SourceFile == source && !claimed[other.ID]) {
+					changes.Skipped++
+					continue
+				}
+				claimed[other.ID] = true
+				current = other
+			}
+
+			deck, ok := decks[word.Category]
+			if !ok {
+				deck, err = ensureDeck(tx, sr.cfg, word.Category)
+				if errors.Is(err, ErrEmptyDeckName) {
+					changes.Skipped++
+					continue
+				}
+				if err != nil {
+					return err
+				}
+				decks[word.Category] = deck
+			}
+			taken[text] = true
+			word.DeckID = &deck.ID
+			word.Category = deck.Path
+			word.SourceFile = source
+			word.SourceKey = key
+
+			if current == nil {
+				word.ID = 0
+				word.CreatedAt = now
+				word.RetiredAt = nil
+				byKey[key] = &word
+				inserts = append(inserts, word)
+				continue
+			}
+
+			restored := current.RetiredAt != nil
+			if current.SourceFile != source || restored {
+				adopted = append(adopted, current.ID)
+			}
+			if current.SourceKey != key {
+				keys[current.ID] = key
+			}
+			if restored || seedChanged(*current, word) {
+				word.ID = current.ID
+				updates = append(updates, word)
+			}
+		}
+
+		var retired []uint
+		for _, word := range existing {
+			if !claimed[word.ID] && word.RetiredAt == nil {
+				retired = append(retired, word.ID)
+			}
+		}
+
+		// Translations are inserted along with their words
+		if len(inserts) > 0 {
+			if err := tx.CreateInBatches(&inserts, seedBatchSize).Error; err != nil {
+				return err
+			}
+		}
+		for ids := range slices.Chunk(adopted, seedBatchSize) {
+			if err := tx.Model(&models.Word{}).Where("id IN ?", ids).
+				Updates(map[string]interface{}{"source_file": source, "retired_at": nil}).Error; err != nil {
+				return err
+			}
+		}
+		if err := updateSourceKeys(tx, keys); err != nil {
+			return err
+		}
+		for i := range updates {
+			if err := updateWord(tx, &updates[i]); err != nil {
+				return err
+			}
+		}
+		for ids := range slices.Chunk(retired, seedBatchSize) {
+			if err := tx.Model(&models.Word{}).Where("id IN ?", ids).Update("retired_at", now).Error; err != nil {
+				return err
+			}
+		}
+		// New, restored and moved words get their cards, for every user at once
+		if len(inserts) > 0 || len(updates) > 0 || len(adopted) > 0 {
+			if err := createCards(tx, now, "words.source_file = ?", source); err != nil {
+				return err
+			}
+		}
+
+		changes.Inserted = len(inserts)
+		changes.Updated = len(updates)
+		changes.Retired = len(retired)
+		return nil
+	})
+	return changes, err
+}
+
+// wordsByText Get the words with the given lowercase texts, for each text the oldest one that is not retired, or else
+// the oldest retired one. A word added again after its data file retired it takes the text over from the retired word.
+func wordsByText(tx *gorm.DB, texts []string) (map[string]*models.Word, error) {
+	byText := make(map[string]*models.Word, len(texts))
+	for chunk := range slices.Chunk(texts, seedBatchSize) {
+		var words []models.Word
+		if err := tx.Preload("Translations", orderTranslations).
+			Where("LOWER(word) IN ?", chunk).Order("retired_at IS NOT NULL, id").Find(&words).Error; err != nil {
+			return nil, err
+		}
+		for i := range words {
+			text := strings.ToLower(words[i].Word)
+			if _, ok := byText[text]; !ok {
+				byText[text] = &words[i]
+			}
+		}
+	}
+	return byText, nil
+}
+
+// updateSourceKeys sets the source key of many words with one statement per batch
+func updateSourceKeys(tx *gorm.DB, keys map[uint]string) error {
+	ids := make([]uint, 0, len(keys))
+	for id := range keys {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for chunk := range slices.Chunk(ids, seedBatchSize/2) {
+		var cases strings.Builder
+		vars := make([]interface{}, 0, 2*len(chunk)+1)
+		for _, id := range chunk {
+			cases.WriteString(" WHEN ? THEN ?")
+			vars = append(vars, id, keys[id])
+		}
+		vars = append(vars, chunk)
+		if err := tx.Exec("UPDATE words SET source_key = CASE id"+cases.String()+" END WHERE id IN ?", vars...).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// seedKey returns the key of a seeded word. Words seeded before keys were
+// stored are identified by their text, like words without a key column.
+func seedKey(word models.Word) string {
+	if word.SourceKey != "" {
+		return word.SourceKey
+	}
+	return strings.ToLower(word.Word)
+}
+
+// seedChanged reports whether the seeded fields of a word differ from the stored word
+func seedChanged(current, seeded models.Word) bool {
+	return current.Word != seeded.Word ||
+		!slices.Equal(current.AcceptedTranslations(), seeded.AcceptedTranslations()) ||
+		current.Category != seeded.Category ||
+		current.DeckID == nil || *current.DeckID != *seeded.DeckID ||
+		current.ExampleSentence != seeded.ExampleSentence ||
+		current.ExampleTranslation != seeded.ExampleTranslation ||
+		current.Note != seeded.Note ||
+		current.PartOfSpeech != seeded.PartOfSpeech
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"learning-cards/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+func (sr *SessionRepository) CreateSession(session *models.Session) error {
+	return sr.db.Create(session).Error
+}
+
+// GetSessionByToken Get the session with the given access token hash together with its user
+func (sr *SessionRepository) GetSessionByToken(tokenHash string) (models.Session, error) {
+	var session models.Session
+	err := sr.db.Preload("User").Where("token_hash = ?", tokenHash).First(&session).Error
+	return session, err
+}
+
+func (sr *SessionRepository) GetSessionByRefreshToken(refreshTokenHash string) (models.Session, error) {
+	var session models.Session
+	err := sr.db.Preload("User").Where("refresh_token_hash = ?", refreshTokenHash).First(&session).Error
+	return session, err
+}
+
+// RotateSession Replace the tokens of a session, invalidating the previous ones
+func (sr *SessionRepository) RotateSession(session *models.Session) error {
+	return sr.db.Model(session).Select("TokenHash", "RefreshTokenHash", "ExpiresAt", "RefreshExpiresAt").
+		Updates(session).Error
+}
+
+func (sr *SessionRepository) DeleteSession(id uint) error {
+	return sr.db.Delete(&models.Session{}, id).Error
+}
+
+// DeleteExpiredSessions Remove sessions that can no longer be refreshed
+func (sr *SessionRepository) DeleteExpiredSessions(now time.Time) (int64, error) {
+	result := sr.db.Where("refresh_expires_at < ?", now).Delete(&models.Session{})
+	return result.RowsAffected, result.Error
+}
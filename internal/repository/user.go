@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"learning-cards/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type UserRepository struct {
+	db *gorm.DB
+}
+
+func NewUserRepository(db *gorm.DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+func (ur *UserRepository) GetUsers() ([]models.User, error) {
+	var users []models.User
+	if err := ur.db.Order("id").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (ur *UserRepository) GetUser(id uint) (models.User, error) {
+	var user models.User
+	err := ur.db.First(&user, id).Error
+	return user, err
+}
+
+func (ur *UserRepository) GetUserByUsername(username string) (models.User, error) {
+	var user models.User
+	err := ur.db.Where("username = ?", username).First(&user).Error
+	return user, err
+}
+
+// CreateUser Create a user, or return gorm.ErrDuplicatedKey when the username is taken, also by a concurrent request
+func (ur *UserRepository) CreateUser(user *models.User) error {
+	result := ur.db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "username"}}, DoNothing: true}).Create(user)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrDuplicatedKey
+	}
+	return nil
+}
+
+// SetPassword Replace the password of a user and end all of its sessions, in one transaction
+func (ur *UserRepository) SetPassword(userID uint, passwordHash string) error {
+	return ur.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Update("password_hash", passwordHash).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.Session{}).Error
+	})
+}
+
+// SetAdmin Grant or revoke admin rights of a user
+func (ur *UserRepository) SetAdmin(username string, isAdmin bool) error {
+	result := ur.db.Model(&models.User{}).Where("username = ?", username).Update("is_admin", isAdmin)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -3,130 +3,205 @@ package repository
 import (
 	"fmt"
 	"learning-cards/internal/models"
-	"log"
+	"learning-cards/internal/scheduler"
+	"learning-cards/internal/utils"
 	"time"
 
 	"gorm.io/gorm"
 )
 
 type UserWordRepository struct {
-	db *gorm.DB
+	db        *gorm.DB
+	scheduler scheduler.Scheduler
 }
 
-func NewUserWordRepository(db *gorm.DB) *UserWordRepository {
-	return &UserWordRepository{db: db}
+func NewUserWordRepository(db *gorm.DB, sched scheduler.Scheduler) *UserWordRepository {
+	return &UserWordRepository{db: db, scheduler: sched}
 }
-func (ur *UserWordRepository) GetUserWords() ([]models.UserWord, error) {
+
+// WithTx returns a copy of the repository that runs its queries in tx
+func (ur *UserWordRepository) WithTx(tx *gorm.DB) *UserWordRepository {
+	return &UserWordRepository{db: tx, scheduler: ur.scheduler}
+}
+func (ur *UserWordRepository) GetUserWords(userID uint) ([]models.UserWord, error) {
 	var userWords []models.UserWord
-	if err := ur.db.Preload("Word").Find(&userWords).Error; err != nil {
+	query := activeCards(joinDecks(preloadWord(ur.db)))
+	if err := query.Where("user_words.user_id = ?", userID).Find(&userWords).Error; err != nil {
 		return nil, err
 	}
 	return userWords, nil
 }
 
-func (ur *UserWordRepository) GetWordsDueToday() ([]models.UserWord, error) {
+func (ur *UserWordRepository) GetWordsDueToday(userID uint) ([]models.UserWord, error) {
 	var userWords []models.UserWord
 	now := time.Now()
 
-	if err := ur.db.Preload("Word").Where("next_review <= ?", now).Find(&userWords).Error; err != nil {
+	query := activeCards(joinDecks(preloadWord(ur.db)))
+	if err := query.
+		Where("user_words.user_id = ? AND user_words.next_review <= ?", userID, now).
+		Find(&userWords).Error; err != nil {
 		return nil, err
 	}
 	return userWords, nil
 }
 
-// GetAllWords Get all the words that are on the word table
-func (ur *UserWordRepository) GetAllWords() ([]models.Word, error) {
-	var words []models.Word
-	if err := ur.db.Find(&words).Error; err != nil {
-		return nil, err
-	}
-	return words, nil
+// GetUserWord Get an active card of the user together with its word
+func (ur *UserWordRepository) GetUserWord(userID uint, card models.Card) (models.UserWord, error) {
+	var userWord models.UserWord
+	err := whereActiveCard(preloadWord(ur.db), userID, card).First(&userWord).Error
+	return userWord, err
 }
 
-// GetUserWordsFromCategory Get all the words that are from the category selected
-func (ur *UserWordRepository) GetUserWordsByCategory(category string) ([]models.UserWord, error) {
+// GetUserWordsFromCategory Get all the words that are from the category selected,
+// optionally including the words of all its sub-decks
+func (ur *UserWordRepository) GetUserWordsByCategory(userID uint, category string, includeDescendants bool) ([]models.UserWord, error) {
 	var userWords []models.UserWord
 	now := time.Now()
-	if err := ur.db.Preload("Word").
-		Where("next_review <= ?", now).
-		Joins("INNER JOIN words ON user_words.word_id = words.id").
-		Where("words.category = ?", category).
-		Find(&userWords).Error; err != nil {
+	query := activeCards(joinDecks(preloadWord(ur.db))).
+		Where("user_words.user_id = ? AND user_words.next_review <= ?", userID, now)
+	query = whereCategory(query, category, includeDescendants)
+	if err := query.Find(&userWords).Error; err != nil {
 		return nil, err
 	}
 	return userWords, nil
 }
 
-// AddUserWord Add a new user word to the user_word table
-func (ur *UserWordRepository) AddUserWord(wordID uint) error {
-	userWord := models.UserWord{
-		WordID:            wordID,
-		BoxNumber:         1,
-		LastReview:        time.Now(),
-		NextReview:        time.Now(),
-		CorrectAttempts:   0,
-		IncorrectAttempts: 0,
+// GetTranslationCards Get the active translation cards of the user, of every word or only
+// of those in a category and its sub-decks, in creation order
+func (ur *UserWordRepository) GetTranslationCards(userID uint, category string) ([]models.UserWord, error) {
+	var userWords []models.UserWord
+	query := activeCards(joinDecks(ur.db.Select("user_words.*"))).
+		Where("user_words.user_id = ? AND user_words.card_type = ?", userID, models.CardTypeTranslation)
+	if category != "" {
+		query = whereCategory(query, category, true)
 	}
-	err := ur.db.Create(&userWord).Error
-	if err != nil {
-		fmt.Printf("Error creating user word: %v", err)
+	if err := query.Order("user_words.id").Find(&userWords).Error; err != nil {
+		return nil, err
 	}
-	return err
+	return userWords, nil
 }
-func (ur *UserWordRepository) UpdateLearningStatus(wordID uint, learned bool) error {
-	var userWord models.UserWord
 
-	if err := ur.db.Where("word_id = ?", wordID).First(&userWord).Error; err != nil {
-		return err
-	}
+// SyncCards Insert every enabled card the user does not have yet, in one transaction
+func (ur *UserWordRepository) SyncCards(userID uint) error {
+	return ur.db.Transaction(func(tx *gorm.DB) error {
+		return createCards(tx, time.Now(), "users.id = ?", userID)
+	})
+}
 
-	now := time.Now()
-	userWord.LastReview = now
+// SyncAllCards Insert every enabled card any user does not have yet, in one transaction
+func (ur *UserWordRepository) SyncAllCards() error {
+	return ur.db.Transaction(func(tx *gorm.DB) error {
+		return createCards(tx, time.Now(), "1 = 1")
+	})
+}
 
-	if !learned {
-		// When the user failed a word, it goes directly to the first box
-		userWord.IncorrectAttempts++
-		userWord.BoxNumber = 1
-		userWord.NextReview = now.Add(24 * time.Hour)
-	} else {
-		if userWord.BoxNumber < 5 {
-			userWord.BoxNumber++
+// UpdateLearningStatus reschedules an active card according to the grade of the answer
+// and records the answer in the review log within the same transaction
+func (ur *UserWordRepository) UpdateLearningStatus(userID uint, card models.Card, grade models.Grade, responseTime time.Duration) error {
+	return ur.db.Transaction(func(tx *gorm.DB) error {
+		var userWord models.UserWord
+		if err := whereActiveCard(tx, userID, card).First(&userWord).Error; err != nil {
+			return err
 		}
-		userWord.NextReview = calculateNextReview(userWord.BoxNumber, now)
+		return ur.applyReview(tx, &userWord, scheduler.Review{
+			Grade:        grade,
+			ResponseTime: responseTime,
+			ReviewedAt:   time.Now(),
+		})
+	})
+}
+
+// ImportReviews replays answers given elsewhere, oldest first, on a card that
+// was never reviewed, so it ends up where the scheduler would have put it.
+// It reports false and changes nothing when the card has been reviewed already.
+func (ur *UserWordRepository) ImportReviews(userID uint, card models.Card, reviews []scheduler.Review) (bool, error) {
+	imported := false
+	err := ur.db.Transaction(func(tx *gorm.DB) error {
+		var userWord models.UserWord
+		if err := whereCard(tx, userID, card).First(&userWord).Error; err != nil {
+			return err
+		}
+		var reviewed int64
+		if err := tx.Model(&models.ReviewLog{}).Where("user_word_id = ?", userWord.ID).Count(&reviewed).Error; err != nil {
+			return err
+		}
+		if reviewed > 0 || userWord.CorrectAttempts+userWord.IncorrectAttempts > 0 {
+			return nil
+		}
+		for i, review := range reviews {
+			if i == 0 {
+				// There is no previous review to count elapsed days from
+				userWord.LastReview = review.ReviewedAt
+			}
+			if err := ur.applyReview(tx, &userWord, review); err != nil {
+				return err
+			}
+		}
+		imported = len(reviews) > 0
+		return nil
+	})
+	return imported, err
+}
+
+// applyReview reschedules a user word and records the review in the log
+func (ur *UserWordRepository) applyReview(tx *gorm.DB, userWord *models.UserWord, review scheduler.Review) error {
+	reviewLog := models.ReviewLog{
+		UserWordID:           userWord.ID,
+		ReviewedAt:           review.ReviewedAt,
+		Grade:                review.Grade,
+		PreviousBox:          userWord.BoxNumber,
+		PreviousIntervalDays: userWord.IntervalDays,
+		ElapsedDays:          review.ReviewedAt.Sub(userWord.LastReview).Hours() / 24,
+		ResponseTimeMs:       uint(review.ResponseTime.Milliseconds()),
+	}
+
+	*userWord = ur.scheduler.Schedule(*userWord, review)
+	userWord.LastReview = review.ReviewedAt
+	if review.Grade.Passed() {
 		userWord.CorrectAttempts++
+	} else {
+		userWord.IncorrectAttempts++
 	}
 
-	if err := ur.db.Save(&userWord).Error; err != nil {
+	if err := tx.Save(userWord).Error; err != nil {
 		fmt.Printf("Error updating user word: %v", err)
 		return err
 	}
-	return nil
+
+	reviewLog.NewBox = userWord.BoxNumber
+	reviewLog.NewIntervalDays = userWord.IntervalDays
+	return tx.Create(&reviewLog).Error
 }
 
-func (ur *UserWordRepository) CheckUserWordExists(wordID uint) (bool, error) {
-	var count int64
-	err := ur.db.Model(&models.UserWord{}).Where("word_id = ?", wordID).Count(&count).Error
-	if err != nil {
-		log.Printf("Error checking existence of word %d: %v", wordID, err)
-		return false, err
+// whereCategory filters a query joined with words and decks on a category given
+// by deck path, name or slug. Sub-decks share the slug of their parent as prefix.
+func whereCategory(query *gorm.DB, category string, includeDescendants bool) *gorm.DB {
+	slug := utils.SlugifyPath(category)
+	if includeDescendants {
+		return query.Where("decks.slug = ? OR decks.slug LIKE ? OR words.category = ?",
+			slug, slug+utils.DeckPathSeparator+"%", category)
 	}
+	return query.Where("decks.slug = ? OR words.category = ?", slug, category)
+}
 
-	return count > 0, nil
-}
-
-func calculateNextReview(boxNumber uint, currentTime time.Time) time.Time {
-	switch boxNumber {
-	case 1:
-		return currentTime.Add(24 * time.Hour)
-	case 2:
-		return currentTime.Add(3 * 24 * time.Hour)
-	case 3:
-		return currentTime.Add(7 * 24 * time.Hour)
-	case 4:
-		return currentTime.Add(14 * 24 * time.Hour)
-	case 5:
-		return currentTime.Add(30 * 24 * time.Hour)
-	default:
-		return currentTime
-	}
+// preloadWord loads the word of user words with its accepted translations
+func preloadWord(query *gorm.DB) *gorm.DB {
+	return query.Preload("Word").Preload("Word.Translations", orderTranslations).Preload("Word.Media", orderMedia)
+}
+
+// orderMedia lists media in upload order
+func orderMedia(query *gorm.DB) *gorm.DB {
+	return query.Order("id")
+}
+
+// orderTranslations puts the primary translation first
+func orderTranslations(query *gorm.DB) *gorm.DB {
+	return query.Order("is_primary DESC, id")
+}
+
+// joinDecks joins a user word query with the words and their decks
+func joinDecks(query *gorm.DB) *gorm.DB {
+	return query.
+		Joins("INNER JOIN words ON user_words.word_id = words.id").
+		Joins("LEFT JOIN decks ON words.deck_id = decks.id")
 }
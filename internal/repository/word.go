@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"learning-cards/internal/models"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type WordRepository struct {
+	db *gorm.DB
+}
+
+func NewWordRepository(db *gorm.DB) *WordRepository {
+	return &WordRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that runs its queries in tx
+func (wr *WordRepository) WithTx(tx *gorm.DB) *WordRepository {
+	return &WordRepository{db: tx}
+}
+
+// Transaction runs fn in a transaction, for changes that span several repositories
+func (wr *WordRepository) Transaction(fn func(tx *gorm.DB) error) error {
+	return wr.db.Transaction(fn)
+}
+
+// GetWords Get all words that are not retired, optionally only those of a category given by deck name or slug,
+// with or without the words of its sub-decks
+func (wr *WordRepository) GetWords(category string, includeDescendants bool) ([]models.Word, error) {
+	var words []models.Word
+	query := wr.db.Preload("Translations", orderTranslations).Preload("Media", orderMedia).
+		Where(activeWordCondition).Order("words.id")
+	if category != "" {
+		query = whereCategory(query.Joins("LEFT JOIN decks ON words.deck_id = decks.id"), category, includeDescendants)
+	}
+	if err := query.Find(&words).Error; err != nil {
+		return nil, err
+	}
+	return words, nil
+}
+
+func (wr *WordRepository) GetWord(id uint) (models.Word, error) {
+	var word models.Word
+	err := wr.db.Preload("Translations", orderTranslations).Preload("Media", orderMedia).First(&word, id).Error
+	return word, err
+}
+
+// FindWordByText Get a word that is not retired with the same text ignoring case, other than excludeID.
+// Retired words no longer count, so that a word removed from a data file can be added again.
+func (wr *WordRepository) FindWordByText(text string, excludeID uint) (models.Word, error) {
+	var word models.Word
+	err := wr.db.Preload("Translations", orderTranslations).
+		Where("LOWER(word) = LOWER(?) AND id <> ?", text, excludeID).Where(activeWordCondition).First(&word).Error
+	return word, err
+}
+
+// CreateWord Insert a word and its cards for every user so it can be studied right away
+func (wr *WordRepository) CreateWord(word *models.Word) error {
+	return wr.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(word).Error; err != nil {
+			return err
+		}
+		return createCards(tx, time.Now(), "words.id = ?", word.ID)
+	})
+}
+
+// UpdateWord Save the fields of a word and replace its accepted translations. Cards the word gets from its new deck,
+// gender or example sentence are added for every user right away.
+func (wr *WordRepository) UpdateWord(word *models.Word) error {
+	return wr.db.Transaction(func(tx *gorm.DB) error {
+		if err := updateWord(tx, word); err != nil {
+			return err
+		}
+		return createCards(tx, time.Now(), "words.id = ?", word.ID)
+	})
+}
+
+func updateWord(tx *gorm.DB, word *models.Word) error {
+	if err := tx.Model(word).
+		Select("Word", "Translation", "Category", "Gender", "TranslationGender",
+			"ExampleSentence", "ExampleTranslation", "Note", "PartOfSpeech", "DeckID").
+		Updates(word).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("word_id = ?", word.ID).Delete(&models.WordTranslation{}).Error; err != nil {
+		return err
+	}
+	for i := range word.Translations {
+		word.Translations[i].ID = 0
+		word.Translations[i].WordID = word.ID
+	}
+	if len(word.Translations) == 0 {
+		return nil
+	}
+	return tx.Create(&word.Translations).Error
+}
+
+// DeleteWord Delete a word together with its translations, media and the user words and review logs that refer to it.
+// Media files are content-addressed and may be shared, so it returns the names of the files that no other word uses
+// anymore, to be deleted once the transaction is committed.
+func (wr *WordRepository) DeleteWord(id uint) ([]string, error) {
+	var unused []string
+	err := wr.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		unused, err = deleteWord(tx, id)
+		return err
+	})
+	return unused, err
+}
+
+func deleteWord(tx *gorm.DB, id uint) ([]string, error) {
+	userWordIDs := tx.Model(&models.UserWord{}).Select("id").Where("word_id = ?", id)
+	if err := tx.Where("user_word_id IN (?)", userWordIDs).Delete(&models.ReviewLog{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("word_id = ?", id).Delete(&models.UserWord{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("word_id = ?", id).Delete(&models.WordTranslation{}).Error; err != nil {
+		return nil, err
+	}
+	var media []models.Media
+	if err := tx.Where("word_id = ?", id).Find(&media).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("word_id = ?", id).Delete(&models.Media{}).Error; err != nil {
+		return nil, err
+	}
+	unused, err := unusedMedia(tx, media)
+	if err != nil {
+		return nil, err
+	}
+	result := tx.Delete(&models.Word{}, id)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return unused, nil
+}
+
+// unusedMedia returns the names of the files of deleted media that no remaining media uses
+func unusedMedia(tx *gorm.DB, deleted []models.Media) ([]string, error) {
+	if len(deleted) == 0 {
+		return nil, nil
+	}
+	hashes := make([]string, 0, len(deleted))
+	for _, item := range deleted {
+		hashes = append(hashes, item.Hash)
+	}
+	var remaining []string
+	if err := tx.Model(&models.Media{}).Distinct("hash").Where("hash IN ?", hashes).Pluck("hash", &remaining).Error; err != nil {
+		return nil, err
+	}
+	var unused []string
+	for _, item := range deleted {
+		if !slices.Contains(remaining, item.Hash) && !slices.Contains(unused, item.Name) {
+			unused = append(unused, item.Name)
+		}
+	}
+	return unused, nil
+}
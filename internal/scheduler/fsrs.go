@@ -0,0 +1,163 @@
+package scheduler
+
+import (
+	"fmt"
+	"learning-cards/internal/models"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	fsrsDecay       = -0.5
+	fsrsFactor      = 19.0 / 81.0
+	fsrsMaxInterval = 36500
+	fsrsWeightCount = 17
+)
+
+// DefaultFSRSWeights are the FSRS-4.5 default parameters, used until weights
+// fitted on our own review history are configured.
+var DefaultFSRSWeights = []float64{
+	0.4872, 1.4003, 3.7145, 13.8206, 5.1618, 1.2298, 0.8975, 0.031,
+	1.6474, 0.1367, 1.0461, 2.1072, 0.0793, 0.3246, 1.587, 0.2272, 2.8755,
+}
+
+// fsrsBounds keeps the weights inside the ranges used by the reference
+// optimizer, both for configured and for fitted weights.
+var fsrsBounds = [fsrsWeightCount][2]float64{
+	{0.1, 100}, {0.1, 100}, {0.1, 100}, {0.1, 100},
+	{1, 10}, {0.1, 5}, {0.1, 5}, {0, 0.5},
+	{0, 3}, {0.1, 0.8}, {0.01, 2.5}, {0.5, 5},
+	{0.01, 0.2}, {0.01, 0.9}, {0.01, 2}, {0, 1}, {1, 6},
+}
+
+// FSRS implements the Free Spaced Repetition Scheduler (version 4.5). Every
+// card keeps a memory stability (days until recall probability drops to 90%)
+// and a difficulty between 1 and 10; the next review is planned for the moment
+// the predicted recall probability reaches the target retention.
+type FSRS struct {
+	Weights         []float64
+	TargetRetention float64
+}
+
+// NewFSRS validates the weights and the target retention.
+func NewFSRS(weights []float64, targetRetention float64) (FSRS, error) {
+	if len(weights) != fsrsWeightCount {
+		return FSRS{}, fmt.Errorf("fsrs needs %d weights, got %d", fsrsWeightCount, len(weights))
+	}
+	for i, w := range weights {
+		if w < fsrsBounds[i][0] || w > fsrsBounds[i][1] {
+			return FSRS{}, fmt.Errorf("fsrs weight %d = %g is outside [%g, %g]", i, w, fsrsBounds[i][0], fsrsBounds[i][1])
+		}
+	}
+	if targetRetention < 0.7 || targetRetention > 0.99 {
+		return FSRS{}, fmt.Errorf("fsrs target retention %g must be between 0.7 and 0.99", targetRetention)
+	}
+	return FSRS{Weights: weights, TargetRetention: targetRetention}, nil
+}
+
+// ParseFSRSWeights parses a comma separated list of weights.
+func ParseFSRSWeights(s string) ([]float64, error) {
+	parts := strings.Split(s, ",")
+	weights := make([]float64, 0, len(parts))
+	for _, part := range parts {
+		w, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fsrs weight %q: %w", part, err)
+		}
+		weights = append(weights, w)
+	}
+	return weights, nil
+}
+
+// FormatFSRSWeights renders weights in the format accepted by ParseFSRSWeights.
+func FormatFSRSWeights(weights []float64) string {
+	parts := make([]string, len(weights))
+	for i, w := range weights {
+		parts[i] = strconv.FormatFloat(w, 'f', 4, 64)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (FSRS) Name() string {
+	return AlgorithmFSRS
+}
+
+func (f FSRS) Schedule(card models.UserWord, review Review) models.UserWord {
+	grade := review.Grade
+	if card.Stability <= 0 {
+		card.Stability = fsrsInitialStability(f.Weights, grade)
+		card.Difficulty = fsrsInitialDifficulty(f.Weights, grade)
+	} else {
+		elapsed := elapsedDays(card.LastReview, review.ReviewedAt)
+		card.Stability, card.Difficulty = fsrsNextState(f.Weights, card.Stability, card.Difficulty, elapsed, grade)
+	}
+
+	if grade == models.GradeAgain {
+		card.Repetitions = 0
+	} else {
+		card.Repetitions++
+	}
+	card.IntervalDays = f.interval(card.Stability)
+	card.BoxNumber = boxForInterval(card.IntervalDays)
+	card.NextReview = addDays(review.ReviewedAt, card.IntervalDays)
+	return card
+}
+
+// Retrievability is the predicted probability of recalling a card with the
+// given stability after elapsedDays.
+func Retrievability(elapsedDays, stability float64) float64 {
+	return math.Pow(1+fsrsFactor*elapsedDays/stability, fsrsDecay)
+}
+
+func (f FSRS) interval(stability float64) uint {
+	days := stability / fsrsFactor * (math.Pow(f.TargetRetention, 1/fsrsDecay) - 1)
+	return uint(math.Min(math.Max(math.Round(days), 1), fsrsMaxInterval))
+}
+
+func fsrsInitialStability(w []float64, grade models.Grade) float64 {
+	return math.Max(w[grade-1], 0.1)
+}
+
+func fsrsInitialDifficulty(w []float64, grade models.Grade) float64 {
+	return clampDifficulty(w[4] - float64(grade-3)*w[5])
+}
+
+// fsrsNextState returns the stability and difficulty after reviewing a card
+// elapsed days after its previous review.
+func fsrsNextState(w []float64, stability, difficulty, elapsed float64, grade models.Grade) (float64, float64) {
+	r := Retrievability(elapsed, stability)
+
+	nextDifficulty := w[7]*fsrsInitialDifficulty(w, models.GradeGood) +
+		(1-w[7])*(difficulty-w[6]*float64(grade-3))
+
+	var nextStability float64
+	if grade == models.GradeAgain {
+		nextStability = w[11] * math.Pow(difficulty, -w[12]) *
+			(math.Pow(stability+1, w[13]) - 1) * math.Exp(w[14]*(1-r))
+		nextStability = math.Min(nextStability, stability)
+	} else {
+		hardPenalty, easyBonus := 1.0, 1.0
+		if grade == models.GradeHard {
+			hardPenalty = w[15]
+		}
+		if grade == models.GradeEasy {
+			easyBonus = w[16]
+		}
+		nextStability = stability * (1 + math.Exp(w[8])*(11-difficulty)*math.Pow(stability, -w[9])*
+			(math.Exp(w[10]*(1-r))-1)*hardPenalty*easyBonus)
+	}
+	return math.Max(nextStability, 0.1), clampDifficulty(nextDifficulty)
+}
+
+func clampDifficulty(d float64) float64 {
+	return math.Min(math.Max(d, 1), 10)
+}
+
+func elapsedDays(from, to time.Time) float64 {
+	if from.IsZero() || to.Before(from) {
+		return 0
+	}
+	return to.Sub(from).Hours() / 24
+}
@@ -0,0 +1,125 @@
+package scheduler
+
+import (
+	"errors"
+	"learning-cards/internal/models"
+	"math"
+)
+
+const (
+	fsrsFitIterations   = 200
+	fsrsFitLearningRate = 0.01
+	fsrsFitMinReviews   = 50
+)
+
+var ErrNotEnoughHistory = errors.New("not enough review history to fit fsrs weights")
+
+// FSRSReview is one past answer of a card, as stored in the review history.
+type FSRSReview struct {
+	Grade models.Grade
+	// ElapsedDays since the previous review of the same card.
+	ElapsedDays float64
+}
+
+// FSRSFit is the outcome of fitting weights on a review history.
+type FSRSFit struct {
+	Weights     []float64
+	InitialLoss float64
+	Loss        float64
+	Reviews     int
+}
+
+// FitFSRS fits FSRS weights on the review history of many cards, each history
+// in chronological order. It minimises the log loss between the predicted
+// retrievability and the actual outcome of every review after the first one,
+// using Adam on numerical gradients in a space normalised by the weight bounds.
+func FitFSRS(histories [][]FSRSReview, initial []float64) (FSRSFit, error) {
+	if len(initial) != fsrsWeightCount {
+		return FSRSFit{}, errors.New("fsrs fit needs a full set of initial weights")
+	}
+	reviews := 0
+	for _, history := range histories {
+		if len(history) > 1 {
+			reviews += len(history) - 1
+		}
+	}
+	if reviews < fsrsFitMinReviews {
+		return FSRSFit{}, ErrNotEnoughHistory
+	}
+
+	u := make([]float64, fsrsWeightCount)
+	for i, w := range initial {
+		u[i] = normalizeWeight(i, w)
+	}
+	loss := func(u []float64) float64 {
+		return fsrsLoss(denormalizeWeights(u), histories)
+	}
+
+	fit := FSRSFit{InitialLoss: loss(u), Reviews: reviews}
+	m := make([]float64, fsrsWeightCount)
+	v := make([]float64, fsrsWeightCount)
+	const beta1, beta2, eps, h = 0.9, 0.999, 1e-8, 1e-4
+	for step := 1; step <= fsrsFitIterations; step++ {
+		for i := range u {
+			orig := u[i]
+			u[i] = orig + h
+			up := loss(u)
+			u[i] = orig - h
+			down := loss(u)
+			u[i] = orig
+			grad := (up - down) / (2 * h)
+
+			m[i] = beta1*m[i] + (1-beta1)*grad
+			v[i] = beta2*v[i] + (1-beta2)*grad*grad
+			mHat := m[i] / (1 - math.Pow(beta1, float64(step)))
+			vHat := v[i] / (1 - math.Pow(beta2, float64(step)))
+			u[i] = math.Min(math.Max(orig-fsrsFitLearningRate*mHat/(math.Sqrt(vHat)+eps), 0), 1)
+		}
+	}
+
+	fit.Weights = denormalizeWeights(u)
+	fit.Loss = loss(u)
+	if fit.Loss > fit.InitialLoss {
+		// Never hand back weights that describe the history worse than the input.
+		fit.Weights = append([]float64(nil), initial...)
+		fit.Loss = fit.InitialLoss
+	}
+	return fit, nil
+}
+
+// fsrsLoss is the mean log loss of the recall predictions over all histories.
+func fsrsLoss(w []float64, histories [][]FSRSReview) float64 {
+	var total float64
+	var count int
+	for _, history := range histories {
+		if len(history) < 2 {
+			continue
+		}
+		stability := fsrsInitialStability(w, history[0].Grade)
+		difficulty := fsrsInitialDifficulty(w, history[0].Grade)
+		for _, review := range history[1:] {
+			p := math.Min(math.Max(Retrievability(review.ElapsedDays, stability), 1e-6), 1-1e-6)
+			if review.Grade == models.GradeAgain {
+				total -= math.Log(1 - p)
+			} else {
+				total -= math.Log(p)
+			}
+			count++
+			stability, difficulty = fsrsNextState(w, stability, difficulty, review.ElapsedDays, review.Grade)
+		}
+	}
+	return total / float64(count)
+}
+
+func normalizeWeight(i int, w float64) float64 {
+	lo, hi := fsrsBounds[i][0], fsrsBounds[i][1]
+	return math.Min(math.Max((w-lo)/(hi-lo), 0), 1)
+}
+
+func denormalizeWeights(u []float64) []float64 {
+	w := make([]float64, len(u))
+	for i, x := range u {
+		w[i] = fsrsBounds[i][0] + x*(fsrsBounds[i][1]-fsrsBounds[i][0])
+	}
+	return w
+}
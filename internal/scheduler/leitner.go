@@ -0,0 +1,43 @@
+package scheduler
+
+import "learning-cards/internal/models"
+
+// leitnerIntervals holds the review interval in days for each box.
+var leitnerIntervals = []uint{1, 3, 7, 14, 30}
+
+// Leitner is the classic five box system: a correct answer moves the card one
+// box up, a wrong answer sends it back to the first box. An easy answer skips a
+// box and a hard one keeps the card in its box with half the interval.
+type Leitner struct{}
+
+func (Leitner) Name() string {
+	return AlgorithmLeitner
+}
+
+func (Leitner) Schedule(card models.UserWord, review Review) models.UserWord {
+	maxBox := uint(len(leitnerIntervals))
+	switch review.Grade {
+	case models.GradeAgain:
+		// When the user failed a word, it goes directly to the first box
+		card.BoxNumber = 1
+	case models.GradeHard:
+		card.BoxNumber = min(max(card.BoxNumber, 1), maxBox)
+	case models.GradeEasy:
+		card.BoxNumber = min(card.BoxNumber+2, maxBox)
+	default:
+		card.BoxNumber = min(card.BoxNumber+1, maxBox)
+	}
+	card.IntervalDays = leitnerInterval(card.BoxNumber)
+	if review.Grade == models.GradeHard {
+		card.IntervalDays = max(card.IntervalDays/2, 1)
+	}
+	card.NextReview = addDays(review.ReviewedAt, card.IntervalDays)
+	return card
+}
+
+func leitnerInterval(boxNumber uint) uint {
+	if boxNumber == 0 || boxNumber > uint(len(leitnerIntervals)) {
+		return 0
+	}
+	return leitnerIntervals[boxNumber-1]
+}
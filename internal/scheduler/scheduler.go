@@ -0,0 +1,67 @@
+// Package scheduler contains the spaced-repetition algorithms used to decide
+// when a user word has to be reviewed again.
+package scheduler
+
+import (
+	"fmt"
+	"learning-cards/config"
+	"learning-cards/internal/models"
+	"time"
+)
+
+const (
+	AlgorithmLeitner = "leitner"
+	AlgorithmSM2     = "sm2"
+	AlgorithmFSRS    = "fsrs"
+)
+
+// Review is the outcome of a single answer given by the learner.
+type Review struct {
+	Grade        models.Grade
+	ResponseTime time.Duration
+	ReviewedAt   time.Time
+}
+
+// Scheduler computes the next scheduling state of a card after a review.
+// Implementations only touch the scheduling fields (box, interval, next review
+// and algorithm specific state); attempt counters are maintained by the caller.
+type Scheduler interface {
+	Name() string
+	Schedule(card models.UserWord, review Review) models.UserWord
+}
+
+// New returns the scheduler selected by the configuration.
+func New(cfg config.SchedulerConfig) (Scheduler, error) {
+	switch cfg.Algorithm {
+	case "", AlgorithmLeitner:
+		return Leitner{}, nil
+	case AlgorithmSM2:
+		return SM2{}, nil
+	case AlgorithmFSRS:
+		weights := DefaultFSRSWeights
+		if cfg.FSRSWeights != "" {
+			var err error
+			if weights, err = ParseFSRSWeights(cfg.FSRSWeights); err != nil {
+				return nil, err
+			}
+		}
+		return NewFSRS(weights, cfg.TargetRetention)
+	default:
+		return nil, fmt.Errorf("unknown scheduler algorithm %q", cfg.Algorithm)
+	}
+}
+
+func addDays(t time.Time, days uint) time.Time {
+	return t.Add(time.Duration(days) * 24 * time.Hour)
+}
+
+// boxForInterval maps an interval onto the Leitner box with the closest
+// interval, so box based clients keep working with interval based algorithms.
+func boxForInterval(days uint) uint {
+	for i, interval := range leitnerIntervals {
+		if days <= interval {
+			return uint(i + 1)
+		}
+	}
+	return uint(len(leitnerIntervals))
+}
@@ -0,0 +1,160 @@
+package scheduler_test
+
+import (
+	"errors"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"learning-cards/config"
+	"learning-cards/internal/models"
+	"learning-cards/internal/scheduler"
+)
+
+func TestNewSelectsAlgorithm(t *testing.T) {
+	for _, name := range []string{"", scheduler.AlgorithmLeitner, scheduler.AlgorithmSM2, scheduler.AlgorithmFSRS} {
+		s, err := scheduler.New(config.SchedulerConfig{Algorithm: name, TargetRetention: 0.9})
+		if err != nil {
+			t.Fatalf("New(%q) returned error: %v", name, err)
+		}
+		if name != "" && s.Name() != name {
+			t.Fatalf("New(%q) returned scheduler %q", name, s.Name())
+		}
+	}
+	if _, err := scheduler.New(config.SchedulerConfig{Algorithm: "unknown"}); err == nil {
+		t.Fatalf("expected error for unknown algorithm")
+	}
+}
+
+func TestLeitnerMovesBoxes(t *testing.T) {
+	now := time.Now()
+	card := models.UserWord{BoxNumber: 2}
+
+	card = scheduler.Leitner{}.Schedule(card, scheduler.Review{Grade: models.GradeGood, ReviewedAt: now})
+	if card.BoxNumber != 3 {
+		t.Fatalf("expected box 3 after correct answer, got %d", card.BoxNumber)
+	}
+	if want := now.Add(7 * 24 * time.Hour); !card.NextReview.Equal(want) {
+		t.Fatalf("expected next review %v, got %v", want, card.NextReview)
+	}
+
+	card = scheduler.Leitner{}.Schedule(card, scheduler.Review{Grade: models.GradeAgain, ReviewedAt: now})
+	if card.BoxNumber != 1 {
+		t.Fatalf("expected box 1 after wrong answer, got %d", card.BoxNumber)
+	}
+	if want := now.Add(24 * time.Hour); !card.NextReview.Equal(want) {
+		t.Fatalf("expected next review %v, got %v", want, card.NextReview)
+	}
+
+	card.BoxNumber = 5
+	card = scheduler.Leitner{}.Schedule(card, scheduler.Review{Grade: models.GradeGood, ReviewedAt: now})
+	if card.BoxNumber != 5 {
+		t.Fatalf("expected box to stay at 5, got %d", card.BoxNumber)
+	}
+}
+
+func TestLeitnerGrades(t *testing.T) {
+	now := time.Now()
+
+	easy := scheduler.Leitner{}.Schedule(models.UserWord{BoxNumber: 2}, scheduler.Review{Grade: models.GradeEasy, ReviewedAt: now})
+	if easy.BoxNumber != 4 || easy.IntervalDays != 14 {
+		t.Fatalf("expected easy answer to skip a box, got box %d interval %d", easy.BoxNumber, easy.IntervalDays)
+	}
+
+	hard := scheduler.Leitner{}.Schedule(models.UserWord{BoxNumber: 3}, scheduler.Review{Grade: models.GradeHard, ReviewedAt: now})
+	if hard.BoxNumber != 3 || hard.IntervalDays != 3 {
+		t.Fatalf("expected hard answer to keep the box with a shorter interval, got box %d interval %d", hard.BoxNumber, hard.IntervalDays)
+	}
+}
+
+func TestSM2Intervals(t *testing.T) {
+	now := time.Now()
+	card := models.UserWord{BoxNumber: 1, EaseFactor: 2.5}
+
+	wantIntervals := []uint{1, 6, 15}
+	for i, want := range wantIntervals {
+		card = scheduler.SM2{}.Schedule(card, scheduler.Review{Grade: models.GradeGood, ReviewedAt: now})
+		if card.IntervalDays != want {
+			t.Fatalf("review %d: expected interval %d, got %d", i+1, want, card.IntervalDays)
+		}
+	}
+	if card.Repetitions != 3 {
+		t.Fatalf("expected 3 repetitions, got %d", card.Repetitions)
+	}
+
+	card = scheduler.SM2{}.Schedule(card, scheduler.Review{Grade: models.GradeAgain, ReviewedAt: now})
+	if card.Repetitions != 0 || card.IntervalDays != 1 || card.BoxNumber != 1 {
+		t.Fatalf("expected reset after failure, got %+v", card)
+	}
+	if card.EaseFactor >= 2.5 {
+		t.Fatalf("expected ease factor to drop after failure, got %f", card.EaseFactor)
+	}
+}
+
+func TestFSRSSchedulesByStability(t *testing.T) {
+	fsrs, err := scheduler.NewFSRS(scheduler.DefaultFSRSWeights, 0.9)
+	if err != nil {
+		t.Fatalf("NewFSRS returned error: %v", err)
+	}
+	now := time.Now()
+
+	card := fsrs.Schedule(models.UserWord{BoxNumber: 1}, scheduler.Review{Grade: models.GradeGood, ReviewedAt: now})
+	if card.Stability <= 0 || card.Difficulty < 1 || card.Difficulty > 10 {
+		t.Fatalf("expected initial stability and difficulty, got %+v", card)
+	}
+	first := card.IntervalDays
+
+	card.LastReview = now
+	later := now.Add(time.Duration(first) * 24 * time.Hour)
+	card = fsrs.Schedule(card, scheduler.Review{Grade: models.GradeGood, ReviewedAt: later})
+	if card.IntervalDays <= first {
+		t.Fatalf("expected interval to grow after a second success, got %d then %d", first, card.IntervalDays)
+	}
+
+	card.LastReview = later
+	failed := fsrs.Schedule(card, scheduler.Review{Grade: models.GradeAgain, ReviewedAt: later.Add(24 * time.Hour)})
+	if failed.Stability >= card.Stability || failed.IntervalDays >= card.IntervalDays {
+		t.Fatalf("expected failure to shrink stability and interval, got %+v", failed)
+	}
+
+	if _, err := scheduler.NewFSRS(scheduler.DefaultFSRSWeights, 0.5); err == nil {
+		t.Fatalf("expected error for target retention out of range")
+	}
+}
+
+func TestFitFSRSImprovesLoss(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	// Simulate learners whose memory decays twice as slowly as the defaults predict.
+	var histories [][]scheduler.FSRSReview
+	for range 200 {
+		history := []scheduler.FSRSReview{{Grade: models.GradeGood}}
+		stability := 8.0
+		for range 6 {
+			elapsed := float64(1 + rng.IntN(20))
+			grade := models.GradeGood
+			if rng.Float64() > scheduler.Retrievability(elapsed, stability) {
+				grade = models.GradeAgain
+				stability /= 2
+			} else {
+				stability *= 2.5
+			}
+			history = append(history, scheduler.FSRSReview{Grade: grade, ElapsedDays: elapsed})
+		}
+		histories = append(histories, history)
+	}
+
+	fit, err := scheduler.FitFSRS(histories, scheduler.DefaultFSRSWeights)
+	if err != nil {
+		t.Fatalf("FitFSRS returned error: %v", err)
+	}
+	if fit.Loss >= fit.InitialLoss {
+		t.Fatalf("expected fitted loss %f to be below initial loss %f", fit.Loss, fit.InitialLoss)
+	}
+	if _, err := scheduler.NewFSRS(fit.Weights, 0.9); err != nil {
+		t.Fatalf("fitted weights are not valid: %v", err)
+	}
+
+	if _, err := scheduler.FitFSRS(histories[:2], scheduler.DefaultFSRSWeights); !errors.Is(err, scheduler.ErrNotEnoughHistory) {
+		t.Fatalf("expected ErrNotEnoughHistory, got %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package scheduler
+
+import (
+	"learning-cards/internal/models"
+	"math"
+)
+
+const (
+	sm2DefaultEase = 2.5
+	sm2MinimumEase = 1.3
+	sm2MaxBox      = 5
+)
+
+// SM2 implements the SuperMemo 2 algorithm. Every card keeps its own ease
+// factor which grows or shrinks with the quality of the answers.
+type SM2 struct{}
+
+func (SM2) Name() string {
+	return AlgorithmSM2
+}
+
+func (SM2) Schedule(card models.UserWord, review Review) models.UserWord {
+	quality := sm2Quality(review)
+	ease := card.EaseFactor
+	if ease < sm2MinimumEase {
+		ease = sm2DefaultEase
+	}
+
+	if quality >= 3 {
+		switch card.Repetitions {
+		case 0:
+			card.IntervalDays = 1
+		case 1:
+			card.IntervalDays = 6
+		default:
+			card.IntervalDays = uint(math.Round(float64(card.IntervalDays) * ease))
+		}
+		card.Repetitions++
+	} else {
+		card.Repetitions = 0
+		card.IntervalDays = 1
+	}
+
+	q := float64(5 - quality)
+	card.EaseFactor = math.Max(sm2MinimumEase, ease+0.1-q*(0.08+q*0.02))
+	// Keep the box number meaningful for clients that only understand boxes.
+	card.BoxNumber = min(card.Repetitions+1, sm2MaxBox)
+	card.NextReview = addDays(review.ReviewedAt, card.IntervalDays)
+	return card
+}
+
+// sm2Quality maps a grade onto the 0-5 quality scale of SM-2.
+func sm2Quality(review Review) int {
+	switch review.Grade {
+	case models.GradeHard:
+		return 3
+	case models.GradeGood:
+		return 4
+	case models.GradeEasy:
+		return 5
+	default:
+		return 1
+	}
+}
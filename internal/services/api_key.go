@@ -0,0 +1,98 @@
+package services
+
+import (
+	"errors"
+	"learning-cards/internal/models"
+	"learning-cards/internal/repository"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// APIKeyPrefix marks API keys so they can be told apart from session tokens.
+	APIKeyPrefix = "lck_"
+	// Last use is recorded at most once per interval to avoid a write per request
+	apiKeyTouchInterval = time.Minute
+)
+
+var (
+	ErrInvalidAPIKeyName = errors.New("api key name must be between 1 and 255 characters")
+	ErrInvalidScope      = errors.New("invalid scope, want read or review")
+	ErrAPIKeyNotFound    = errors.New("api key not found")
+)
+
+type APIKeyService struct {
+	repo *repository.APIKeyRepository
+}
+
+func NewAPIKeyService(repo *repository.APIKeyRepository) *APIKeyService {
+	return &APIKeyService{repo: repo}
+}
+
+// CreateAPIKey creates a key for the user and returns it with the secret, which
+// is not stored and cannot be retrieved later.
+func (s *APIKeyService) CreateAPIKey(userID uint, name string, scopes []string) (models.APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 255 {
+		return models.APIKey{}, "", ErrInvalidAPIKeyName
+	}
+	for _, scope := range scopes {
+		if scope != models.ScopeRead && scope != models.ScopeReview {
+			return models.APIKey{}, "", ErrInvalidScope
+		}
+	}
+	slices.Sort(scopes)
+	scopes = slices.Compact(scopes)
+
+	token, err := generateToken()
+	if err != nil {
+		return models.APIKey{}, "", err
+	}
+	secret := APIKeyPrefix + token
+	key := models.APIKey{
+		UserID:  userID,
+		Name:    name,
+		Prefix:  secret[:12],
+		KeyHash: hashToken(secret),
+		Scopes:  strings.Join(scopes, ","),
+	}
+	if err := s.repo.CreateAPIKey(&key); err != nil {
+		return models.APIKey{}, "", err
+	}
+	return key, secret, nil
+}
+
+func (s *APIKeyService) GetAPIKeys(userID uint) ([]models.APIKey, error) {
+	return s.repo.GetAPIKeys(userID)
+}
+
+func (s *APIKeyService) RevokeAPIKey(userID, keyID uint) error {
+	err := s.repo.RevokeAPIKey(userID, keyID, time.Now())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrAPIKeyNotFound
+	}
+	return err
+}
+
+// Authenticate returns the active key matching the secret and records its use.
+func (s *APIKeyService) Authenticate(secret string) (models.APIKey, error) {
+	key, err := s.repo.GetActiveAPIKeyByHash(hashToken(secret))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.APIKey{}, ErrInvalidToken
+		}
+		return models.APIKey{}, err
+	}
+
+	now := time.Now()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > apiKeyTouchInterval {
+		if err := s.repo.TouchAPIKey(key.ID, now); err != nil {
+			return models.APIKey{}, err
+		}
+		key.LastUsedAt = &now
+	}
+	return key, nil
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"learning-cards/config"
+	"learning-cards/internal/models"
+	"learning-cards/internal/repository"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	minPasswordLength = 8
+	// bcrypt ignores everything after 72 bytes
+	maxPasswordLength = 72
+)
+
+var (
+	ErrInvalidUsername     = errors.New("username must be between 1 and 255 characters")
+	ErrInvalidPassword     = errors.New("password must be between 8 and 72 characters")
+	ErrUsernameTaken       = errors.New("username already taken")
+	ErrRegistrationClosed  = errors.New("registration is disabled")
+	ErrInvalidCredentials  = errors.New("invalid username or password")
+	ErrInvalidToken        = errors.New("invalid or expired token")
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+)
+
+// Tokens are handed to the client after login or refresh.
+type Tokens struct {
+	AccessToken      string    `json:"access_token"`
+	RefreshToken     string    `json:"refresh_token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+type AuthService struct {
+	users    *repository.UserRepository
+	sessions *repository.SessionRepository
+	cfg      config.AuthConfig
+}
+
+func NewAuthService(users *repository.UserRepository, sessions *repository.SessionRepository, cfg config.AuthConfig) *AuthService {
+	return &AuthService{users: users, sessions: sessions, cfg: cfg}
+}
+
+// Register creates a user with the given password. Existing usernames are
+// rejected, including users without a password like the default user, which
+// can only be claimed with SetPassword.
+func (s *AuthService) Register(username, password string) (models.User, error) {
+	if !s.cfg.AllowRegistration {
+		return models.User{}, ErrRegistrationClosed
+	}
+	username = strings.TrimSpace(username)
+	if username == "" || len(username) > 255 {
+		return models.User{}, ErrInvalidUsername
+	}
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return models.User{}, ErrInvalidPassword
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	user := models.User{Username: username, PasswordHash: string(hash)}
+	if err := s.users.CreateUser(&user); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return models.User{}, ErrUsernameTaken
+		}
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+// SetPassword replaces the password of an existing user and logs it out
+// everywhere, so that a stolen session does not outlive the reset. It is meant
+// for operators, e.g. to claim the default user that holds the legacy progress.
+func (s *AuthService) SetPassword(username, password string) error {
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return ErrInvalidPassword
+	}
+	user, err := s.users.GetUserByUsername(strings.TrimSpace(username))
+	if err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return s.users.SetPassword(user.ID, string(hash))
+}
+
+// Login checks the credentials and opens a new session.
+func (s *AuthService) Login(username, password string) (Tokens, error) {
+	user, err := s.users.GetUserByUsername(strings.TrimSpace(username))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return Tokens{}, ErrInvalidCredentials
+		}
+		return Tokens{}, err
+	}
+	if user.PasswordHash == "" ||
+		bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return Tokens{}, ErrInvalidCredentials
+	}
+
+	session := models.Session{UserID: user.ID}
+	tokens, err := s.issueTokens(&session)
+	if err != nil {
+		return Tokens{}, err
+	}
+	if err := s.sessions.CreateSession(&session); err != nil {
+		return Tokens{}, err
+	}
+	return tokens, nil
+}
+
+// Refresh exchanges a refresh token for a new pair of tokens. The old tokens
+// stop working.
+func (s *AuthService) Refresh(refreshToken string) (Tokens, error) {
+	session, err := s.sessions.GetSessionByRefreshToken(hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return Tokens{}, ErrInvalidRefreshToken
+		}
+		return Tokens{}, err
+	}
+	if time.Now().After(session.RefreshExpiresAt) {
+		return Tokens{}, ErrInvalidRefreshToken
+	}
+
+	tokens, err := s.issueTokens(&session)
+	if err != nil {
+		return Tokens{}, err
+	}
+	if err := s.sessions.RotateSession(&session); err != nil {
+		return Tokens{}, err
+	}
+	return tokens, nil
+}
+
+// Logout ends the session of the given access token.
+func (s *AuthService) Logout(accessToken string) error {
+	session, err := s.sessions.GetSessionByToken(hashToken(accessToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidToken
+		}
+		return err
+	}
+	return s.sessions.DeleteSession(session.ID)
+}
+
+// Authenticate returns the user owning a valid access token.
+func (s *AuthService) Authenticate(accessToken string) (models.User, error) {
+	session, err := s.sessions.GetSessionByToken(hashToken(accessToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.User{}, ErrInvalidToken
+		}
+		return models.User{}, err
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return models.User{}, ErrInvalidToken
+	}
+	return session.User, nil
+}
+
+func (s *AuthService) DeleteExpiredSessions() (int64, error) {
+	return s.sessions.DeleteExpiredSessions(time.Now())
+}
+
+// issueTokens generates fresh tokens and stores their hashes on the session.
+func (s *AuthService) issueTokens(session *models.Session) (Tokens, error) {
+	accessToken, err := generateToken()
+	if err != nil {
+		return Tokens{}, err
+	}
+	refreshToken, err := generateToken()
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	now := time.Now()
+	session.TokenHash = hashToken(accessToken)
+	session.RefreshTokenHash = hashToken(refreshToken)
+	session.ExpiresAt = now.Add(s.cfg.AccessTokenTTL)
+	session.RefreshExpiresAt = now.Add(s.cfg.RefreshTokenTTL)
+	return Tokens{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		ExpiresAt:        session.ExpiresAt,
+		RefreshExpiresAt: session.RefreshExpiresAt,
+	}, nil
+}
+
+func generateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,35 @@
+package services
+
+import (
+	"io"
+	"learning-cards/internal/backup"
+	"learning-cards/internal/repository"
+	"time"
+)
+
+type BackupService struct {
+	repo *repository.BackupRepository
+}
+
+func NewBackupService(repo *repository.BackupRepository) *BackupService {
+	return &BackupService{repo: repo}
+}
+
+// Backup writes the whole collection as NDJSON
+func (s *BackupService) Backup(w io.Writer) error {
+	writer, err := backup.NewWriter(w, time.Now())
+	if err != nil {
+		return err
+	}
+	return s.repo.WriteBackup(writer)
+}
+
+// Restore replaces the collection with a backup once it has been read and
+// validated completely. A dry run only reports the changes.
+func (s *BackupService) Restore(r io.Reader, dryRun bool) (backup.Changes, error) {
+	snapshot, err := backup.Read(r)
+	if err != nil {
+		return backup.Changes{}, err
+	}
+	return s.repo.Restore(snapshot, dryRun)
+}
@@ -0,0 +1,55 @@
+package services_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"learning-cards/config"
+	"learning-cards/internal/backup"
+	"learning-cards/internal/models"
+	"learning-cards/internal/repository"
+	"learning-cards/internal/scheduler"
+	"learning-cards/internal/services"
+)
+
+func TestBackupRoundTrip(t *testing.T) {
+	_, db, _ := setupSeedTest(t)
+	if err := db.AutoMigrate(&models.Session{}, &models.APIKey{}); err != nil {
+		t.Fatalf("auto migrate failed: %v", err)
+	}
+	user := models.User{Username: "ana", PasswordHash: "x"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	deckRepo := repository.NewDeckRepository(db, config.DeckConfig{SourceLanguage: "es", TargetLanguage: "de"})
+	words := services.NewWordService(repository.NewWordRepository(db), deckRepo, nil)
+	word, err := words.CreateWord(models.Word{Word: "el perro", Translation: "der Hund", Category: "animals::pets"})
+	if err != nil {
+		t.Fatalf("failed to create word: %v", err)
+	}
+	card := models.Card{WordID: word.ID, Type: models.CardTypeTranslation, Direction: models.DirectionForward}
+	if err := repository.NewUserWordRepository(db, scheduler.Leitner{}).UpdateLearningStatus(user.ID, card, models.GradeGood, time.Second); err != nil {
+		t.Fatalf("failed to review card: %v", err)
+	}
+
+	backups := services.NewBackupService(repository.NewBackupRepository(db))
+	var saved bytes.Buffer
+	if err := backups.Backup(&saved); err != nil {
+		t.Fatalf("failed to write backup: %v", err)
+	}
+	changes, err := backups.Restore(bytes.NewReader(saved.Bytes()), false)
+	if err != nil {
+		t.Fatalf("failed to restore backup: %v", err)
+	}
+	want := backup.Changes{
+		Users:      backup.Change{Unchanged: 1},
+		Decks:      backup.Change{Unchanged: 2},
+		Words:      backup.Change{Unchanged: 1},
+		UserWords:  backup.Change{Unchanged: 1},
+		ReviewLogs: backup.Change{Unchanged: 1},
+	}
+	if changes != want {
+		t.Fatalf("expected the backup to restore unchanged, got %+v", changes)
+	}
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"learning-cards/internal/models"
+	"learning-cards/internal/repository"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const maxLanguageLength = 16
+
+var ErrInvalidDeck = errors.New("invalid deck")
+
+// DeckChanges holds the fields of a partial deck update, nil fields are kept.
+type DeckChanges struct {
+	Description    *string
+	SourceLanguage *string
+	TargetLanguage *string
+	ReverseCards   *bool
+	GenderCards    *bool
+	ClozeCards     *bool
+}
+
+type DeckService struct {
+	repo *repository.DeckRepository
+}
+
+func NewDeckService(repo *repository.DeckRepository) *DeckService {
+	return &DeckService{repo: repo}
+}
+
+func (s *DeckService) GetDecks(userID uint) ([]repository.DeckSummary, error) {
+	return s.repo.GetDeckSummaries(userID, time.Now())
+}
+
+func (s *DeckService) UpdateDeck(id uint, changes DeckChanges) (models.Deck, error) {
+	deck, err := s.repo.GetDeck(id)
+	if err != nil {
+		return models.Deck{}, err
+	}
+	if changes.Description != nil {
+		deck.Description = strings.TrimSpace(*changes.Description)
+	}
+	if changes.ReverseCards != nil {
+		deck.ReverseCards = *changes.ReverseCards
+	}
+	if changes.GenderCards != nil {
+		deck.GenderCards = *changes.GenderCards
+	}
+	if changes.ClozeCards != nil {
+		deck.ClozeCards = *changes.ClozeCards
+	}
+	for _, language := range []struct {
+		value *string
+		field *string
+		name  string
+	}{
+		{changes.SourceLanguage, &deck.SourceLanguage, "source language"},
+		{changes.TargetLanguage, &deck.TargetLanguage, "target language"},
+	} {
+		if language.value == nil {
+			continue
+		}
+		value := strings.TrimSpace(*language.value)
+		if utf8.RuneCountInString(value) > maxLanguageLength {
+			return models.Deck{}, fmt.Errorf("%w: %s must be at most %d characters", ErrInvalidDeck, language.name, maxLanguageLength)
+		}
+		*language.field = value
+	}
+
+	if err := s.repo.UpdateDeck(&deck); err != nil {
+		return models.Deck{}, err
+	}
+	return deck, nil
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"fmt"
+	"learning-cards/internal/anki"
+	"learning-cards/internal/models"
+	"learning-cards/internal/repository"
+	"learning-cards/internal/utils"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ExportService struct {
+	wordRepo     *repository.WordRepository
+	userWordRepo *repository.UserWordRepository
+}
+
+func NewExportService(wordRepo *repository.WordRepository, userWordRepo *repository.UserWordRepository) *ExportService {
+	return &ExportService{wordRepo: wordRepo, userWordRepo: userWordRepo}
+}
+
+// ExportAnki collects the words of a category and its sub-decks, or of every
+// category when it is empty, as Anki notes. The translation cards of the user
+// become the front and reverse card of a note with their scheduling state and
+// reviews, so the learner can continue in Anki where they left off.
+func (s *ExportService) ExportAnki(userID uint, category string) (*anki.Collection, error) {
+	words, err := s.wordRepo.GetWords(category, true)
+	if err != nil {
+		return nil, err
+	}
+	if category != "" && len(words) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	userWords, err := s.userWordRepo.GetTranslationCards(userID, category)
+	if err != nil {
+		return nil, err
+	}
+	reviewLogs, err := s.userWordRepo.GetCategoryReviewLogs(userID, category)
+	if err != nil {
+		return nil, err
+	}
+
+	reviews := make(map[uint][]anki.Review)
+	for _, reviewLog := range reviewLogs {
+		reviews[reviewLog.UserWordID] = append(reviews[reviewLog.UserWordID], anki.Review{
+			At:           reviewLog.ReviewedAt,
+			Ease:         int(reviewLog.Grade),
+			Duration:     time.Duration(reviewLog.ResponseTimeMs) * time.Millisecond,
+			Interval:     int(reviewLog.NewIntervalDays),
+			LastInterval: int(reviewLog.PreviousIntervalDays),
+		})
+	}
+	cards := make(map[uint][]anki.Card)
+	for _, userWord := range userWords {
+		cards[userWord.WordID] = append(cards[userWord.WordID], ankiCard(userWord, reviews[userWord.ID]))
+	}
+
+	collection := &anki.Collection{Notes: make([]anki.Note, 0, len(words))}
+	for _, word := range words {
+		note := anki.Note{
+			GUID: fmt.Sprintf("learning-cards-%d", word.ID),
+			Deck: word.Category,
+			Fields: []anki.Field{
+				{Name: "Front", Value: word.Word},
+				{Name: "Back", Value: strings.Join(word.AcceptedTranslations(), " "+utils.TranslationSeparator+" ")},
+			},
+			Cards: cards[word.ID],
+		}
+		if word.PartOfSpeech != "" {
+			note.Tags = []string{word.PartOfSpeech}
+		}
+		if len(note.Cards) == 0 {
+			// Every note needs a card to be imported
+			note.Cards = []anki.Card{{Ord: 0}}
+		}
+		collection.Notes = append(collection.Notes, note)
+	}
+	return collection, nil
+}
+
+// ankiCard converts a translation card, the forward card being the front one.
+// Cards that were never answered stay new.
+func ankiCard(userWord models.UserWord, reviews []anki.Review) anki.Card {
+	card := anki.Card{Ord: 0, Reviews: reviews}
+	if userWord.Direction == models.DirectionReverse {
+		card.Ord = 1
+	}
+	attempts := userWord.CorrectAttempts + userWord.IncorrectAttempts
+	if attempts == 0 && len(reviews) == 0 {
+		return card
+	}
+	card.Due = userWord.NextReview
+	card.Interval = int(userWord.IntervalDays)
+	card.Factor = int(userWord.EaseFactor * 1000)
+	card.Reps = int(max(attempts, uint(len(reviews))))
+	card.Lapses = int(userWord.IncorrectAttempts)
+	return card
+}
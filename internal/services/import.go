@@ -0,0 +1,315 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"learning-cards/internal/anki"
+	"learning-cards/internal/models"
+	"learning-cards/internal/repository"
+	"learning-cards/internal/scheduler"
+	"learning-cards/internal/utils"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// AnkiImportOptions selects the note fields holding the word and its
+// translation by name, by default the first and second field of a note
+type AnkiImportOptions struct {
+	WordField        string
+	TranslationField string
+	// History replays the Anki review log of the imported words on the cards of UserID
+	History bool
+	UserID  uint
+}
+
+// ImportConflict is a note whose word already exists with other translations
+type ImportConflict struct {
+	Word                string `json:"word"`
+	Translation         string `json:"translation"`
+	ExistingWordID      uint   `json:"existing_word_id"`
+	ExistingTranslation string `json:"existing_translation"`
+}
+
+// ImportReport counts what happened to the notes of an import. Skipped notes
+// were already known or could not be turned into a word.
+type ImportReport struct {
+	Imported    int              `json:"imported"`
+	Skipped     int              `json:"skipped"`
+	Conflicting int              `json:"conflicting"`
+	Reviews     int              `json:"reviews"`
+	Conflicts   []ImportConflict `json:"conflicts"`
+}
+
+// Problems of a CSV line besides the ones of FieldError
+const (
+	ProblemFieldCount = "wrong_field_count"
+	ProblemInvalid    = "invalid"
+	ProblemDuplicate  = "duplicate"
+)
+
+// CSVIssue is a problem with one line of an uploaded CSV. Field is the column
+// the problem was found in, if any.
+type CSVIssue struct {
+	Line    int    `json:"line"`
+	Field   string `json:"field,omitempty"`
+	Problem string `json:"problem"`
+	Message string `json:"message"`
+	// DuplicateOf is the earlier line of the same upload with the same word
+	DuplicateOf    int  `json:"duplicate_of,omitempty"`
+	ExistingWordID uint `json:"existing_word_id,omitempty"`
+}
+
+// CSVImportReport counts the lines of an uploaded CSV. Invalid lines have at
+// least one issue, skipped lines hold a word that is already known. In a
+// preview Imported counts the words that would be imported.
+type CSVImportReport struct {
+	Preview  bool       `json:"preview"`
+	Lines    int        `json:"lines"`
+	Imported int        `json:"imported"`
+	Skipped  int        `json:"skipped"`
+	Invalid  int        `json:"invalid"`
+	Issues   []CSVIssue `json:"issues"`
+}
+
+type ImportService struct {
+	words        *WordService
+	userWordRepo *repository.UserWordRepository
+}
+
+func NewImportService(words *WordService, userWordRepo *repository.UserWordRepository) *ImportService {
+	return &ImportService{words: words, userWordRepo: userWordRepo}
+}
+
+// ImportAnki creates a word for every note of the collection, in the category
+// of its deck. Cloze notes have no translation and are skipped. The import runs
+// in one transaction, so a failure leaves no part of the collection behind.
+func (s *ImportService) ImportAnki(collection *anki.Collection, options AnkiImportOptions) (ImportReport, error) {
+	var report ImportReport
+	err := s.words.repo.Transaction(func(tx *gorm.DB) error {
+		report = ImportReport{Conflicts: []ImportConflict{}}
+		words := s.words.withTx(tx)
+		userWordRepo := s.userWordRepo.WithTx(tx)
+		for _, note := range collection.Notes {
+			text, translation, ok := noteWord(note, options)
+			if !ok || note.Cloze {
+				report.Skipped++
+				continue
+			}
+
+			// Exported words list their accepted translations like the CSV files
+			translations := utils.SplitTranslations(translation)
+			if len(translations) == 0 {
+				report.Skipped++
+				continue
+			}
+			translation = translations[0]
+			word := models.Word{Word: text, Category: note.Deck}
+			word.SetTranslations(translation, translations[1:])
+			created, err := words.CreateWord(word)
+			var duplicate *DuplicateWordError
+			switch {
+			case errors.As(err, &duplicate):
+				if hasTranslation(duplicate.Existing, translation) {
+					report.Skipped++
+					continue
+				}
+				report.Conflicting++
+				report.Conflicts = append(report.Conflicts, ImportConflict{
+					Word:                text,
+					Translation:         translation,
+					ExistingWordID:      duplicate.Existing.ID,
+					ExistingTranslation: duplicate.Existing.Translation,
+				})
+				continue
+			case errors.Is(err, ErrInvalidWord):
+				report.Skipped++
+				continue
+			case err != nil:
+				return err
+			}
+			report.Imported++
+
+			if options.History {
+				reviews, err := importHistory(userWordRepo, options.UserID, created.ID, note)
+				if err != nil {
+					return err
+				}
+				report.Reviews += reviews
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return ImportReport{Conflicts: []ImportConflict{}}, err
+	}
+	return report, nil
+}
+
+// ImportCSV creates a word for every valid row of a words CSV and reports the
+// problems of the other rows. A preview only checks the rows and writes nothing.
+func (s *ImportService) ImportCSV(rows []utils.Row, preview bool) (CSVImportReport, error) {
+	report := CSVImportReport{Preview: preview, Lines: len(rows), Issues: []CSVIssue{}}
+	seen := make(map[string]int, len(rows))
+	for _, row := range rows {
+		if row.Err != nil {
+			report.Invalid++
+			report.Issues = append(report.Issues, CSVIssue{Line: row.Line, Problem: ProblemFieldCount, Message: row.Err.Error()})
+			continue
+		}
+		word := normalizeWord(row.Word)
+		if issues := csvIssues(row.Line, word); len(issues) > 0 {
+			report.Invalid++
+			report.Issues = append(report.Issues, issues...)
+			continue
+		}
+
+		key := strings.ToLower(word.Word)
+		if line, ok := seen[key]; ok {
+			report.Skipped++
+			report.Issues = append(report.Issues, CSVIssue{
+				Line:        row.Line,
+				Field:       utils.ColumnWord,
+				Problem:     ProblemDuplicate,
+				Message:     fmt.Sprintf("word %q already appears on line %d", word.Word, line),
+				DuplicateOf: line,
+			})
+			continue
+		}
+		seen[key] = row.Line
+
+		if preview {
+			existing, err := s.words.FindWord(word.Word)
+			switch {
+			case err == nil:
+				report.Skipped++
+				report.Issues = append(report.Issues, existingWordIssue(row.Line, existing))
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				report.Imported++
+			default:
+				return report, err
+			}
+			continue
+		}
+
+		_, err := s.words.CreateWord(word)
+		var duplicate *DuplicateWordError
+		switch {
+		case errors.As(err, &duplicate):
+			report.Skipped++
+			report.Issues = append(report.Issues, existingWordIssue(row.Line, duplicate.Existing))
+		case errors.Is(err, ErrInvalidWord):
+			report.Invalid++
+			report.Issues = append(report.Issues, CSVIssue{Line: row.Line, Problem: ProblemInvalid, Message: err.Error()})
+		case err != nil:
+			return report, err
+		default:
+			report.Imported++
+		}
+	}
+	return report, nil
+}
+
+// csvIssues checks the fields of a normalized word from a CSV. Unlike words
+// created through the API, words from a CSV need a category.
+func csvIssues(line int, word models.Word) []CSVIssue {
+	var issues []CSVIssue
+	for _, problem := range checkFields(word) {
+		field := problem.Field
+		if field == "alternatives" {
+			field = utils.ColumnTranslation
+		}
+		issues = append(issues, CSVIssue{Line: line, Field: field, Problem: problem.Problem, Message: problem.Message})
+	}
+
+	segments := utils.SplitDeckPath(word.Category)
+	if len(segments) == 0 {
+		issues = append(issues, CSVIssue{Line: line, Field: utils.ColumnCategory, Problem: ProblemEmpty, Message: "category must not be empty"})
+	}
+	for _, segment := range segments {
+		if utils.Slugify(segment) == "" {
+			issues = append(issues, CSVIssue{Line: line, Field: utils.ColumnCategory, Problem: ProblemInvalid, Message: "category must contain letters or digits"})
+			break
+		}
+	}
+	return issues
+}
+
+func existingWordIssue(line int, existing models.Word) CSVIssue {
+	return CSVIssue{
+		Line:           line,
+		Field:          utils.ColumnWord,
+		Problem:        ProblemDuplicate,
+		Message:        (&DuplicateWordError{Existing: existing}).Error(),
+		ExistingWordID: existing.ID,
+	}
+}
+
+// importHistory replays the reviews of the front card on the forward card of
+// the word and those of the reverse card on the reverse one, if the word has it
+func importHistory(userWordRepo *repository.UserWordRepository, userID, wordID uint, note anki.Note) (int, error) {
+	count := 0
+	for _, card := range note.Cards {
+		if len(card.Reviews) == 0 || card.Ord > 1 {
+			continue
+		}
+		direction := models.DirectionForward
+		if card.Ord == 1 {
+			direction = models.DirectionReverse
+		}
+		reviews := make([]scheduler.Review, len(card.Reviews))
+		for i, review := range card.Reviews {
+			reviews[i] = scheduler.Review{
+				// Anki's answer buttons match the grades from again to easy
+				Grade:        models.Grade(review.Ease),
+				ResponseTime: review.Duration,
+				ReviewedAt:   review.At,
+			}
+		}
+		target := models.Card{WordID: wordID, Type: models.CardTypeTranslation, Direction: direction}
+		imported, err := userWordRepo.ImportReviews(userID, target, reviews)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return count, err
+		}
+		if imported {
+			count += len(reviews)
+		}
+	}
+	return count, nil
+}
+
+// noteWord returns the plain text of the word and translation fields of a note
+func noteWord(note anki.Note, options AnkiImportOptions) (string, string, bool) {
+	word, ok := noteField(note, options.WordField, 0)
+	if !ok {
+		return "", "", false
+	}
+	translation, ok := noteField(note, options.TranslationField, 1)
+	if !ok {
+		return "", "", false
+	}
+	return anki.PlainText(word), anki.PlainText(translation), true
+}
+
+// noteField returns the field with the given name, or the one at position when no name is given
+func noteField(note anki.Note, name string, position int) (string, bool) {
+	if name != "" {
+		return note.Field(name)
+	}
+	if position >= len(note.Fields) {
+		return "", false
+	}
+	return note.Fields[position].Value, true
+}
+
+func hasTranslation(word models.Word, translation string) bool {
+	for _, accepted := range word.AcceptedTranslations() {
+		if strings.EqualFold(accepted, translation) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"learning-cards/config"
+	"learning-cards/internal/media"
+	"learning-cards/internal/models"
+	"learning-cards/internal/repository"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+var ErrMediaTooLarge = errors.New("media too large")
+
+type MediaService struct {
+	repo     *repository.MediaRepository
+	wordRepo *repository.WordRepository
+	store    media.Store
+	cfg      config.MediaConfig
+}
+
+func NewMediaService(repo *repository.MediaRepository, wordRepo *repository.WordRepository, store media.Store, cfg config.MediaConfig) *MediaService {
+	return &MediaService{repo: repo, wordRepo: wordRepo, store: store, cfg: cfg}
+}
+
+// MaxSize is the largest file that can be uploaded, in bytes
+func (s *MediaService) MaxSize() int64 {
+	return s.cfg.MaxSize
+}
+
+func (s *MediaService) GetWordMedia(wordID uint) ([]models.Media, error) {
+	if _, err := s.wordRepo.GetWord(wordID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetWordMedia(wordID)
+}
+
+// Upload attaches an image or audio file to a word. The type is sniffed from
+// the content. Identical content is stored once, and uploading it again for
+// the same word returns the existing media.
+func (s *MediaService) Upload(wordID uint, filename string, content io.Reader) (models.Media, error) {
+	if _, err := s.wordRepo.GetWord(wordID); err != nil {
+		return models.Media{}, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(content, s.cfg.MaxSize+1))
+	if err != nil {
+		return models.Media{}, err
+	}
+	if int64(len(data)) > s.cfg.MaxSize {
+		return models.Media{}, fmt.Errorf("%w: at most %d bytes", ErrMediaTooLarge, s.cfg.MaxSize)
+	}
+	mediaType, err := media.Detect(data)
+	if err != nil {
+		return models.Media{}, err
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	existing, err := s.repo.FindWordMedia(wordID, hash)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.Media{}, err
+	}
+
+	name := media.Name(hash, mediaType.Extension)
+	stored, err := s.store.Exists(name)
+	if err != nil {
+		return models.Media{}, err
+	}
+	if !stored {
+		if err := s.store.Save(name, bytes.NewReader(data)); err != nil {
+			return models.Media{}, err
+		}
+	}
+
+	item := models.Media{
+		WordID:    wordID,
+		Kind:      mediaType.Kind,
+		MimeType:  mediaType.MimeType,
+		Hash:      hash,
+		Name:      name,
+		Size:      int64(len(data)),
+		Filename:  truncate(filepath.Base(strings.TrimSpace(filename)), maxWordFieldLength),
+		CreatedAt: time.Now(),
+	}
+	if err := s.repo.CreateMedia(&item); err != nil {
+		return models.Media{}, err
+	}
+	return item, nil
+}
+
+// Open opens a stored file by name together with a media that uses it
+func (s *MediaService) Open(name string) (io.ReadSeekCloser, models.Media, error) {
+	item, err := s.repo.GetMediaByName(name)
+	if err != nil {
+		return nil, models.Media{}, err
+	}
+	file, err := s.store.Open(name)
+	if err != nil {
+		return nil, models.Media{}, err
+	}
+	return file, item, nil
+}
+
+// DeleteMedia detaches a media from its word and deletes the file once no
+// other word uses it
+func (s *MediaService) DeleteMedia(id uint) error {
+	item, err := s.repo.GetMedia(id)
+	if err != nil {
+		return err
+	}
+	shared, err := s.repo.DeleteMedia(item)
+	if err != nil {
+		return err
+	}
+	if !shared {
+		deleteMediaFiles(s.store, item.Name)
+	}
+	return nil
+}
+
+// deleteMediaFiles deletes files that no media uses anymore. The rows are
+// already gone, so a file that fails to delete is only logged.
+func deleteMediaFiles(store media.Store, names ...string) {
+	for _, name := range names {
+		if err := store.Delete(name); err != nil {
+			log.Printf("failed to delete media file %s: %v", name, err)
+		}
+	}
+}
+
+func truncate(s string, maxRunes int) string {
+	if utf8.RuneCountInString(s) <= maxRunes {
+		return s
+	}
+	return string([]rune(s)[:maxRunes])
+}
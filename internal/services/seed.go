@@ -0,0 +1,148 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"learning-cards/internal/models"
+	"learning-cards/internal/repository"
+	"learning-cards/internal/utils"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// SeedService keeps the words seeded from the CSV files of a data directory
+// in line with the files.
+type SeedService struct {
+	repo *repository.SeedRepository
+	dir  string
+	// mu serializes syncs, the watcher and cron may sync the same file at once
+	mu sync.Mutex
+}
+
+func NewSeedService(repo *repository.SeedRepository, dir string) *SeedService {
+	return &SeedService{repo: repo, dir: dir}
+}
+
+// Dir returns the data directory
+func (s *SeedService) Dir() string {
+	return s.dir
+}
+
+// SyncFile applies the words of a CSV file of the data directory: it inserts
+// new words, updates changed ones and retires the words that were removed
+// from it. Words are identified by the key column, or by their text in files
+// without one. A file that no longer exists retires all of its words. A file
+// with invalid lines changes nothing, so that a mistake retires no words.
+func (s *SeedService) SyncFile(path string) (repository.SeedChanges, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.syncFile(path)
+}
+
+func (s *SeedService) syncFile(path string) (repository.SeedChanges, error) {
+	source, err := s.source(path)
+	if err != nil {
+		return repository.SeedChanges{}, err
+	}
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return s.repo.SyncSource(source, nil)
+	}
+	words, err := readSeedFile(path)
+	if err != nil {
+		return repository.SeedChanges{}, err
+	}
+	return s.repo.SyncSource(source, words)
+}
+
+// SyncAll syncs every CSV file of the data directory and retires the words of
+// files that are gone. Files that fail are reported together at the end.
+func (s *SeedService) SyncAll() (repository.SeedChanges, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var total repository.SeedChanges
+	var errs []error
+	synced := make(map[string]bool)
+	err := filepath.WalkDir(s.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || filepath.Ext(path) != ".csv" {
+			return nil
+		}
+		source, err := s.source(path)
+		if err != nil {
+			return err
+		}
+		synced[source] = true
+		changes, err := s.syncFile(path)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		total.Add(changes)
+		return nil
+	})
+	if err != nil {
+		// Without a complete list of files no words are retired
+		return total, errors.Join(append(errs, err)...)
+	}
+
+	sources, err := s.repo.GetSources()
+	if err != nil {
+		return total, errors.Join(append(errs, err)...)
+	}
+	for _, source := range sources {
+		if synced[source] {
+			continue
+		}
+		changes, err := s.syncFile(filepath.Join(s.dir, filepath.FromSlash(source)))
+		if err != nil {
+			errs = append(errs, err)
+		}
+		total.Add(changes)
+	}
+	return total, errors.Join(errs...)
+}
+
+// source returns the path of a file relative to the data directory, as stored in Word.SourceFile
+func (s *SeedService) source(path string) (string, error) {
+	rel, err := filepath.Rel(s.dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is not in the data directory %s", path, s.dir)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// readSeedFile reads the words of a CSV file and checks them like an upload.
+// Every invalid line is reported.
+func readSeedFile(path string) ([]models.Word, error) {
+	rows, err := utils.ReadCSVRows(path)
+	if err != nil {
+		return nil, err
+	}
+	var words []models.Word
+	var errs []error
+	for _, row := range rows {
+		if row.Err != nil {
+			errs = append(errs, &utils.LineError{File: path, Line: row.Line, Err: row.Err})
+			continue
+		}
+		word := normalizeWord(row.Word)
+		for _, issue := range csvIssues(row.Line, word) {
+			errs = append(errs, &utils.LineError{File: path, Line: row.Line, Err: errors.New(issue.Message)})
+		}
+		if utf8.RuneCountInString(row.Key) > maxWordFieldLength {
+			errs = append(errs, &utils.LineError{File: path, Line: row.Line, Err: fmt.Errorf("key must be at most %d characters", maxWordFieldLength)})
+		}
+		word.SourceKey = row.Key
+		words = append(words, word)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return words, nil
+}
@@ -0,0 +1,355 @@
+package services_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"learning-cards/config"
+	"learning-cards/internal/models"
+	"learning-cards/internal/repository"
+	"learning-cards/internal/scheduler"
+	"learning-cards/internal/services"
+	"learning-cards/internal/utils"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupSeedTest(t testing.TB) (*services.SeedService, *gorm.DB, string) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite DB: %v", err)
+	}
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		_ = sqlDB.Close()
+	})
+	if err := db.AutoMigrate(&models.User{}, &models.Deck{}, &models.Word{}, &models.WordTranslation{}, &models.Media{}, &models.UserWord{}, &models.ReviewLog{}); err != nil {
+		t.Fatalf("auto migrate failed: %v", err)
+	}
+	dir := t.TempDir()
+	repo := repository.NewSeedRepository(db, config.DeckConfig{SourceLanguage: "es", TargetLanguage: "de"})
+	return services.NewSeedService(repo, dir), db, dir
+}
+
+func writeCSV(t testing.TB, path string, lines ...string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func TestSeedSyncFile(t *testing.T) {
+	seeds, db, dir := setupSeedTest(t)
+	if err := db.Create(&models.User{Username: "ana", PasswordHash: "x"}).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	// Seeded before files were tracked and decks existed, taken over by the file
+	legacy := models.Word{Word: "rojo", Translation: "rot", Category: "colors"}
+	legacy.SetTranslations("rot", nil)
+	if err := db.Create(&legacy).Error; err != nil {
+		t.Fatalf("failed to create word: %v", err)
+	}
+
+	path := filepath.Join(dir, "colors.csv")
+	writeCSV(t, path, "word,translation,category", "rojo,rot,colors", "azul,blau,colors", "verde,grün,colors")
+	changes, err := seeds.SyncFile(path)
+	if err != nil {
+		t.Fatalf("SyncFile returned error: %v", err)
+	}
+	if changes != (repository.SeedChanges{Inserted: 2, Updated: 1}) {
+		t.Fatalf("unexpected changes: %+v", changes)
+	}
+	var cards int64
+	db.Model(&models.UserWord{}).Count(&cards)
+	if cards != 3 {
+		t.Fatalf("expected a card for each of the 3 words, got %d", cards)
+	}
+	var adopted models.Word
+	db.First(&adopted, legacy.ID)
+	if adopted.SourceFile != "colors.csv" || adopted.DeckID == nil {
+		t.Fatalf("expected rojo to be taken over by colors.csv, got %+v", adopted)
+	}
+
+	writeCSV(t, path, "word,translation,category", "rojo,rot|rötlich,colors", "verde,grün,colors", "negro,schwarz,colors")
+	if changes, err = seeds.SyncFile(path); err != nil {
+		t.Fatalf("SyncFile returned error: %v", err)
+	}
+	if changes != (repository.SeedChanges{Inserted: 1, Updated: 1, Retired: 1}) {
+		t.Fatalf("unexpected changes: %+v", changes)
+	}
+	var rojo models.Word
+	db.Preload("Translations").First(&rojo, legacy.ID)
+	if len(rojo.Translations) != 2 {
+		t.Fatalf("expected rojo to have 2 translations, got %+v", rojo.Translations)
+	}
+	var azul models.Word
+	if err := db.Where("word = ?", "azul").First(&azul).Error; err != nil || azul.RetiredAt == nil {
+		t.Fatalf("expected azul to be retired, got %+v, %v", azul, err)
+	}
+	db.Model(&models.UserWord{}).Where("word_id = ?", azul.ID).Count(&cards)
+	if cards != 1 {
+		t.Fatalf("expected the card of azul to be kept, got %d", cards)
+	}
+	listed, err := repository.NewWordRepository(db).GetWords("", false)
+	if err != nil {
+		t.Fatalf("GetWords returned error: %v", err)
+	}
+	for _, word := range listed {
+		if word.ID == azul.ID {
+			t.Fatalf("expected retired azul not to be listed")
+		}
+	}
+
+	// A mistake in the file changes nothing
+	writeCSV(t, path, "word,translation,category", "rojo,rot,colors", "verde,grün")
+	if _, err = seeds.SyncFile(path); err == nil || !strings.Contains(err.Error(), path+":3:") {
+		t.Fatalf("expected an error on line 3 of %s, got %v", path, err)
+	}
+	var count int64
+	db.Model(&models.Word{}).Where("source_file = ? AND retired_at IS NULL", "colors.csv").Count(&count)
+	if count != 3 {
+		t.Fatalf("expected the 3 words of colors.csv to stay active, got %d", count)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("failed to remove %s: %v", path, err)
+	}
+	if changes, err = seeds.SyncFile(path); err != nil {
+		t.Fatalf("SyncFile returned error: %v", err)
+	}
+	if changes != (repository.SeedChanges{Retired: 3}) {
+		t.Fatalf("unexpected changes after removing the file: %+v", changes)
+	}
+}
+
+func TestSeedSyncKeys(t *testing.T) {
+	seeds, db, dir := setupSeedTest(t)
+	path := filepath.Join(dir, "animals.csv")
+	writeCSV(t, path, "key,word,translation,category", "dog,el pero,der Hund,animals", "cat,el gato,die Katze,animals")
+	if _, err := seeds.SyncFile(path); err != nil {
+		t.Fatalf("SyncFile returned error: %v", err)
+	}
+	var dog models.Word
+	if err := db.Where("source_key = ?", "dog").First(&dog).Error; err != nil {
+		t.Fatalf("expected a word with key dog: %v", err)
+	}
+
+	// Fixing the text keeps the word, removing and adding back a line restores it
+	writeCSV(t, path, "key,word,translation,category", "dog,el perro,der Hund,animals")
+	changes, err := seeds.SyncFile(path)
+	if err != nil {
+		t.Fatalf("SyncFile returned error: %v", err)
+	}
+	if changes != (repository.SeedChanges{Updated: 1, Retired: 1}) {
+		t.Fatalf("unexpected changes: %+v", changes)
+	}
+	var renamed models.Word
+	db.First(&renamed, dog.ID)
+	if renamed.Word != "el perro" || renamed.RetiredAt != nil {
+		t.Fatalf("expected el pero to be renamed to el perro, got %+v", renamed)
+	}
+
+	writeCSV(t, path, "key,word,translation,category", "dog,el perro,der Hund,animals", "cat,el gato,die Katze,animals", "cat,la gata,die Katze,animals")
+	if changes, err = seeds.SyncFile(path); err != nil {
+		t.Fatalf("SyncFile returned error: %v", err)
+	}
+	if changes != (repository.SeedChanges{Updated: 1, Skipped: 1}) {
+		t.Fatalf("unexpected changes: %+v", changes)
+	}
+	var count int64
+	db.Model(&models.Word{}).Where("retired_at IS NULL").Count(&count)
+	if count != 2 {
+		t.Fatalf("expected 2 active words, got %d", count)
+	}
+}
+
+func TestSeedSyncAll(t *testing.T) {
+	seeds, db, dir := setupSeedTest(t)
+	writeCSV(t, filepath.Join(dir, "animals.csv"), "word,translation,category", "el perro,der Hund,animals")
+	writeCSV(t, filepath.Join(dir, "food", "fruit.csv"), "word,translation,category", "la naranja,die Orange,food::fruit", "el perro,der Hund,animals")
+	changes, err := seeds.SyncAll()
+	if err != nil {
+		t.Fatalf("SyncAll returned error: %v", err)
+	}
+	if changes != (repository.SeedChanges{Inserted: 2, Skipped: 1}) {
+		t.Fatalf("unexpected changes: %+v", changes)
+	}
+	var naranja models.Word
+	if err := db.Where("word = ?", "la naranja").First(&naranja).Error; err != nil || naranja.SourceFile != "food/fruit.csv" {
+		t.Fatalf("expected la naranja from food/fruit.csv, got %+v, %v", naranja, err)
+	}
+
+	if err := os.RemoveAll(filepath.Join(dir, "food")); err != nil {
+		t.Fatalf("failed to remove directory: %v", err)
+	}
+	if changes, err = seeds.SyncAll(); err != nil {
+		t.Fatalf("SyncAll returned error: %v", err)
+	}
+	if changes != (repository.SeedChanges{Retired: 1}) {
+		t.Fatalf("unexpected changes after removing a file: %+v", changes)
+	}
+}
+
+const (
+	benchmarkWords = 2000
+	benchmarkUsers = 5
+)
+
+// setupSeedBenchmark writes a data file with benchmarkWords words and creates benchmarkUsers users
+func setupSeedBenchmark(b *testing.B) (*services.SeedService, *gorm.DB, string) {
+	seeds, db, dir := setupSeedTest(b)
+	db.Logger = logger.Discard
+	lines := []string{"word,translation,category"}
+	for i := 0; i < benchmarkWords; i++ {
+		lines = append(lines, fmt.Sprintf("palabra %d,Wort %d|Begriff %d,topic %d", i, i, i, i%20))
+	}
+	path := filepath.Join(dir, "words.csv")
+	writeCSV(b, path, lines...)
+	for i := 0; i < benchmarkUsers; i++ {
+		if err := db.Create(&models.User{Username: fmt.Sprintf("user%d", i), PasswordHash: "x"}).Error; err != nil {
+			b.Fatalf("failed to create user: %v", err)
+		}
+	}
+	return seeds, db, path
+}
+
+func resetWords(b *testing.B, db *gorm.DB) {
+	b.Helper()
+	for _, table := range []string{"user_words", "word_translations", "words", "decks"} {
+		if err := db.Exec("DELETE FROM " + table).Error; err != nil {
+			b.Fatalf("failed to reset %s: %v", table, err)
+		}
+	}
+}
+
+// seedPerRow seeds like the former insertData job and cron sync, with queries for every row and card
+func seedPerRow(b *testing.B, db *gorm.DB, path string) {
+	words, err := utils.ReadCSVFile(path)
+	if err != nil {
+		b.Fatalf("ReadCSVFile returned error: %v", err)
+	}
+	deckRepo := repository.NewDeckRepository(db, config.DeckConfig{})
+	for _, word := range words {
+		if err := db.Where("word = ?", word.Word).First(&models.Word{}).Error; !errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		deck, err := deckRepo.EnsureDeck(word.Category)
+		if err != nil {
+			b.Fatalf("EnsureDeck returned error: %v", err)
+		}
+		word.DeckID = &deck.ID
+		if err := db.Create(&word).Error; err != nil {
+			b.Fatalf("failed to insert word: %v", err)
+		}
+	}
+	syncPerRow(b, db)
+}
+
+// syncPerRow adds the missing cards like the former SyncUserWords, checking every card of every user
+func syncPerRow(b *testing.B, db *gorm.DB) {
+	var userIDs, wordIDs []uint
+	db.Model(&models.User{}).Pluck("id", &userIDs)
+	db.Model(&models.Word{}).Pluck("id", &wordIDs)
+	for _, userID := range userIDs {
+		for _, wordID := range wordIDs {
+			var count int64
+			if err := db.Model(&models.UserWord{}).
+				Where("user_id = ? AND word_id = ? AND card_type = ? AND direction = ?",
+					userID, wordID, models.CardTypeTranslation, models.DirectionForward).
+				Count(&count).Error; err != nil {
+				b.Fatalf("failed to check card: %v", err)
+			}
+			if count > 0 {
+				continue
+			}
+			now := time.Now()
+			if err := db.Create(&models.UserWord{
+				UserID:     userID,
+				WordID:     wordID,
+				CardType:   models.CardTypeTranslation,
+				Direction:  models.DirectionForward,
+				BoxNumber:  1,
+				LastReview: now,
+				NextReview: now,
+			}).Error; err != nil {
+				b.Fatalf("failed to insert card: %v", err)
+			}
+		}
+	}
+}
+
+func BenchmarkSeed(b *testing.B) {
+	b.Run("per-row", func(b *testing.B) {
+		_, db, path := setupSeedBenchmark(b)
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			resetWords(b, db)
+			b.StartTimer()
+			seedPerRow(b, db, path)
+		}
+	})
+	b.Run("set-based", func(b *testing.B) {
+		seeds, db, path := setupSeedBenchmark(b)
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			resetWords(b, db)
+			b.StartTimer()
+			if _, err := seeds.SyncFile(path); err != nil {
+				b.Fatalf("SyncFile returned error: %v", err)
+			}
+		}
+	})
+	// A sync of an unchanged file, as the cron job runs it every minute locally
+	b.Run("unchanged", func(b *testing.B) {
+		seeds, _, path := setupSeedBenchmark(b)
+		if _, err := seeds.SyncFile(path); err != nil {
+			b.Fatalf("SyncFile returned error: %v", err)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := seeds.SyncFile(path); err != nil {
+				b.Fatalf("SyncFile returned error: %v", err)
+			}
+		}
+	})
+}
+
+func BenchmarkSyncUserWords(b *testing.B) {
+	run := func(b *testing.B, sync func(db *gorm.DB)) {
+		seeds, db, path := setupSeedBenchmark(b)
+		if _, err := seeds.SyncFile(path); err != nil {
+			b.Fatalf("SyncFile returned error: %v", err)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			if err := db.Exec("DELETE FROM user_words").Error; err != nil {
+				b.Fatalf("failed to reset user words: %v", err)
+			}
+			b.StartTimer()
+			sync(db)
+		}
+	}
+	b.Run("per-row", func(b *testing.B) {
+		run(b, func(db *gorm.DB) { syncPerRow(b, db) })
+	})
+	b.Run("set-based", func(b *testing.B) {
+		run(b, func(db *gorm.DB) {
+			userWordService := services.NewUserWordService(repository.NewUserWordRepository(db, scheduler.Leitner{}))
+			if err := userWordService.SyncAllUsers(); err != nil {
+				b.Fatalf("SyncAllUsers returned error: %v", err)
+			}
+		})
+	})
+}
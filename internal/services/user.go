@@ -0,0 +1,27 @@
+package services
+
+import (
+	"learning-cards/internal/models"
+	"learning-cards/internal/repository"
+)
+
+type UserService struct {
+	repo *repository.UserRepository
+}
+
+func NewUserService(repo *repository.UserRepository) *UserService {
+	return &UserService{repo: repo}
+}
+
+func (s *UserService) GetUsers() ([]models.User, error) {
+	return s.repo.GetUsers()
+}
+
+// IsAdmin reports whether the user may administrate the collection
+func (s *UserService) IsAdmin(userID uint) (bool, error) {
+	user, err := s.repo.GetUser(userID)
+	if err != nil {
+		return false, err
+	}
+	return user.IsAdmin, nil
+}
@@ -1,9 +1,16 @@
 package services
 
 import (
+	"errors"
+	"fmt"
+	"learning-cards/internal/answer"
 	"learning-cards/internal/models"
 	"learning-cards/internal/repository"
+	"learning-cards/internal/scheduler"
+	"log"
 	"math/rand"
+	"time"
+	"unicode/utf8"
 )
 
 type UserWordService struct {
@@ -14,39 +21,141 @@ func NewUserWordService(repo *repository.UserWordRepository) *UserWordService {
 	return &UserWordService{repo: repo}
 }
 
-func (s *UserWordService) GetUserWords() ([]models.UserWord, error) {
-	return s.repo.GetUserWords()
+var (
+	ErrNoCloze       = errors.New("the word does not occur in its example sentence")
+	ErrAnswerTooLong = fmt.Errorf("answer must be at most %d characters", answer.MaxLength)
+)
+
+func (s *UserWordService) GetUserWords(userID uint) ([]models.UserWord, error) {
+	userWords, err := s.repo.GetUserWords(userID)
+	if err != nil {
+		return nil, err
+	}
+	return withClozes(userWords), nil
 }
-func (s *UserWordService) GetUserWordsDueToday() ([]models.UserWord, error) {
-	words, err := s.repo.GetWordsDueToday()
+func (s *UserWordService) GetUserWordsDueToday(userID uint) ([]models.UserWord, error) {
+	words, err := s.repo.GetWordsDueToday(userID)
 	if err != nil {
 		return nil, err
 	}
+	words = withClozes(words)
 	// Shuffle the words
 	rand.Shuffle(len(words), func(i, j int) {
 		words[i], words[j] = words[j], words[i]
 	})
 	return words, nil
 }
-func (s *UserWordService) AddUserWord(wordID uint) error {
-	return s.repo.AddUserWord(wordID)
+func (s *UserWordService) UpdateUserWord(userID uint, card models.Card, grade models.Grade, responseTime time.Duration) error {
+	return s.repo.UpdateLearningStatus(userID, card, grade, responseTime)
 }
-func (s *UserWordService) GetAllWords() ([]models.Word, error) {
-	return s.repo.GetAllWords()
-}
-func (s *UserWordService) UpdateUserWord(wordID uint, learned bool) error {
-	return s.repo.UpdateLearningStatus(wordID, learned)
-}
-func (s *UserWordService) CheckUserWordExists(wordID uint) (bool, error) {
-	return s.repo.CheckUserWordExists(wordID)
+
+// AnswerCard checks a typed answer against what the card asks for and
+// reschedules the card with the grade of the verdict
+func (s *UserWordService) AnswerCard(userID uint, card models.Card, typed string, responseTime time.Duration) (answer.Result, error) {
+	if utf8.RuneCountInString(typed) > answer.MaxLength {
+		return answer.Result{}, ErrAnswerTooLong
+	}
+	userWord, err := s.repo.GetUserWord(userID, card)
+	if err != nil {
+		return answer.Result{}, err
+	}
+
+	// Forward cards ask about the translation, reverse cards about the word
+	var result answer.Result
+	switch {
+	case card.Type == models.CardTypeGender && card.Direction == models.DirectionReverse:
+		result = answer.CheckGender(typed, userWord.Word.Word)
+	case card.Type == models.CardTypeGender:
+		result = answer.CheckGender(typed, userWord.Word.Translation)
+	case card.Type == models.CardTypeCloze:
+		cloze, ok := answer.MakeCloze(userWord.Word.ExampleSentence, userWord.Word.Word)
+		if !ok {
+			return answer.Result{}, ErrNoCloze
+		}
+		result = answer.Check(typed, cloze.Answer)
+	case card.Direction == models.DirectionReverse:
+		result = answer.Check(typed, userWord.Word.Word)
+	default:
+		result = answer.CheckAny(typed, userWord.Word.AcceptedTranslations())
+	}
+
+	if err := s.repo.UpdateLearningStatus(userID, card, result.Verdict.Grade(), responseTime); err != nil {
+		return answer.Result{}, err
+	}
+	return result, nil
 }
-func (s *UserWordService) GetUserWordByCategory(category string) ([]models.UserWord, error) {
-	wordByCategory, err := s.repo.GetUserWordsByCategory(category)
+
+func (s *UserWordService) GetUserWordByCategory(userID uint, category string, includeDescendants bool) ([]models.UserWord, error) {
+	wordByCategory, err := s.repo.GetUserWordsByCategory(userID, category, includeDescendants)
 	if err != nil {
 		return nil, err
 	}
+	wordByCategory = withClozes(wordByCategory)
 	rand.Shuffle(len(wordByCategory), func(i, j int) {
 		wordByCategory[i], wordByCategory[j] = wordByCategory[j], wordByCategory[i]
 	})
 	return wordByCategory, nil
 }
+
+// SyncUser adds a user word for every card the user does not study yet. The
+// decks decide which cards their words have.
+func (s *UserWordService) SyncUser(userID uint) error {
+	if err := s.repo.SyncCards(userID); err != nil {
+		return fmt.Errorf("failed to add missing cards: %w", err)
+	}
+	return nil
+}
+
+// SyncAllUsers adds the cards every user does not study yet, like SyncUser
+func (s *UserWordService) SyncAllUsers() error {
+	if err := s.repo.SyncAllCards(); err != nil {
+		return fmt.Errorf("failed to add missing cards: %w", err)
+	}
+	return nil
+}
+
+// withClozes fills the cloze of cloze cards and leaves out the cloze cards
+// whose word cannot be found in its example sentence
+func withClozes(userWords []models.UserWord) []models.UserWord {
+	cards := userWords[:0]
+	for _, userWord := range userWords {
+		if userWord.CardType == models.CardTypeCloze {
+			cloze, ok := answer.MakeCloze(userWord.Word.ExampleSentence, userWord.Word.Word)
+			if !ok {
+				log.Printf("Word %d does not occur in its example sentence, skipping its cloze card.", userWord.WordID)
+				continue
+			}
+			userWord.Cloze = cloze.Text
+		}
+		cards = append(cards, userWord)
+	}
+	return cards
+}
+
+func (s *UserWordService) GetReviewHistory(userID uint, card models.Card) ([]models.ReviewLog, error) {
+	return s.repo.GetReviewHistory(userID, card)
+}
+
+// FitFSRSWeights fits FSRS weights on the review log of all user words,
+// starting from the given weights.
+func (s *UserWordService) FitFSRSWeights(initial []float64) (scheduler.FSRSFit, error) {
+	reviewLogs, err := s.repo.GetAllReviewLogs()
+	if err != nil {
+		return scheduler.FSRSFit{}, err
+	}
+
+	var histories [][]scheduler.FSRSReview
+	var lastUserWordID uint
+	for _, reviewLog := range reviewLogs {
+		if len(histories) == 0 || reviewLog.UserWordID != lastUserWordID {
+			histories = append(histories, nil)
+			lastUserWordID = reviewLog.UserWordID
+		}
+		last := len(histories) - 1
+		histories[last] = append(histories[last], scheduler.FSRSReview{
+			Grade:       reviewLog.Grade,
+			ElapsedDays: reviewLog.ElapsedDays,
+		})
+	}
+	return scheduler.FitFSRS(histories, initial)
+}
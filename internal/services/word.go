@@ -0,0 +1,243 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"learning-cards/internal/media"
+	"learning-cards/internal/models"
+	"learning-cards/internal/repository"
+	"learning-cards/internal/utils"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// maxWordFieldLength matches the size:255 columns of models.Word
+const maxWordFieldLength = 255
+
+// Limits of the example sentences, note and part of speech of a word
+const (
+	maxWordTextLength     = 1000
+	maxPartOfSpeechLength = 32
+)
+
+var ErrInvalidWord = errors.New("invalid word")
+
+// DuplicateWordError is returned when a word with the same text already exists.
+type DuplicateWordError struct {
+	Existing models.Word
+}
+
+func (e *DuplicateWordError) Error() string {
+	return fmt.Sprintf("word %q already exists with id %d", e.Existing.Word, e.Existing.ID)
+}
+
+// WordChanges holds the fields of a partial update, nil fields are kept.
+type WordChanges struct {
+	Word        *string
+	Translation *string
+	// Alternatives replaces the accepted translations other than the primary one
+	Alternatives       *[]string
+	Category           *string
+	ExampleSentence    *string
+	ExampleTranslation *string
+	Note               *string
+	PartOfSpeech       *string
+}
+
+type WordService struct {
+	repo     *repository.WordRepository
+	deckRepo *repository.DeckRepository
+	store    media.Store
+}
+
+func NewWordService(repo *repository.WordRepository, deckRepo *repository.DeckRepository, store media.Store) *WordService {
+	return &WordService{repo: repo, deckRepo: deckRepo, store: store}
+}
+
+// withTx returns a copy of the service that runs its queries in tx
+func (s *WordService) withTx(tx *gorm.DB) *WordService {
+	return &WordService{repo: s.repo.WithTx(tx), deckRepo: s.deckRepo.WithTx(tx), store: s.store}
+}
+
+func (s *WordService) GetWords(category string, includeDescendants bool) ([]models.Word, error) {
+	return s.repo.GetWords(category, includeDescendants)
+}
+
+func (s *WordService) GetWord(id uint) (models.Word, error) {
+	return s.repo.GetWord(id)
+}
+
+// FindWord returns the word with the given text, ignoring case
+func (s *WordService) FindWord(text string) (models.Word, error) {
+	return s.repo.FindWordByText(strings.TrimSpace(text), 0)
+}
+
+func (s *WordService) CreateWord(word models.Word) (models.Word, error) {
+	word = normalizeWord(word)
+	if err := s.validate(word); err != nil {
+		return models.Word{}, err
+	}
+	if err := s.assignDeck(&word); err != nil {
+		return models.Word{}, err
+	}
+	word.ID = 0
+	word.CreatedAt = time.Now()
+	if err := s.repo.CreateWord(&word); err != nil {
+		return models.Word{}, err
+	}
+	return word, nil
+}
+
+func (s *WordService) UpdateWord(id uint, changes WordChanges) (models.Word, error) {
+	word, err := s.repo.GetWord(id)
+	if err != nil {
+		return models.Word{}, err
+	}
+	if changes.Word != nil {
+		word.Word = *changes.Word
+	}
+	if changes.Translation != nil {
+		word.Translation = *changes.Translation
+	}
+	if changes.Alternatives != nil {
+		word.SetTranslations(word.Translation, *changes.Alternatives)
+	}
+	for _, change := range []struct {
+		value *string
+		field *string
+	}{
+		{changes.Category, &word.Category},
+		{changes.ExampleSentence, &word.ExampleSentence},
+		{changes.ExampleTranslation, &word.ExampleTranslation},
+		{changes.Note, &word.Note},
+		{changes.PartOfSpeech, &word.PartOfSpeech},
+	} {
+		if change.value != nil {
+			*change.field = *change.value
+		}
+	}
+
+	word = normalizeWord(word)
+	if err := s.validate(word); err != nil {
+		return models.Word{}, err
+	}
+	if err := s.assignDeck(&word); err != nil {
+		return models.Word{}, err
+	}
+	if err := s.repo.UpdateWord(&word); err != nil {
+		return models.Word{}, err
+	}
+	return word, nil
+}
+
+// DeleteWord deletes a word, and the media files that no other word uses
+func (s *WordService) DeleteWord(id uint) error {
+	unused, err := s.repo.DeleteWord(id)
+	if err != nil {
+		return err
+	}
+	deleteMediaFiles(s.store, unused...)
+	return nil
+}
+
+// assignDeck links the word to the deck of its category, creating the deck if needed
+func (s *WordService) assignDeck(word *models.Word) error {
+	if word.Category == "" {
+		word.DeckID = nil
+		return nil
+	}
+	deck, err := s.deckRepo.EnsureDeck(word.Category)
+	if errors.Is(err, repository.ErrEmptyDeckName) {
+		return fmt.Errorf("%w: category must contain letters or digits", ErrInvalidWord)
+	}
+	if err != nil {
+		return err
+	}
+	word.DeckID = &deck.ID
+	word.Category = deck.Path
+	return nil
+}
+
+func normalizeWord(word models.Word) models.Word {
+	word.Word = strings.TrimSpace(word.Word)
+	alternatives := word.Alternatives()
+	for i := range alternatives {
+		alternatives[i] = strings.TrimSpace(alternatives[i])
+	}
+	word.SetTranslations(strings.TrimSpace(word.Translation), alternatives)
+	word.Category = strings.TrimSpace(word.Category)
+	word.ExampleSentence = strings.TrimSpace(word.ExampleSentence)
+	word.ExampleTranslation = strings.TrimSpace(word.ExampleTranslation)
+	word.Note = strings.TrimSpace(word.Note)
+	word.PartOfSpeech = strings.ToLower(strings.TrimSpace(word.PartOfSpeech))
+	word.Gender = utils.ParseGender(word.Word)
+	word.TranslationGender = utils.ParseGender(word.Translation)
+	return word
+}
+
+// Problems of an invalid field of a word
+const (
+	ProblemEmpty   = "empty"
+	ProblemTooLong = "too_long"
+)
+
+// FieldError is a problem with one field of a word. Field is the name of the
+// field as used by the API and the CSV columns.
+type FieldError struct {
+	Field   string
+	Problem string
+	Message string
+}
+
+// validate checks the fields of a normalized word and that no other word has the same text
+func (s *WordService) validate(word models.Word) error {
+	if problems := checkFields(word); len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidWord, problems[0].Message)
+	}
+
+	existing, err := s.repo.FindWordByText(word.Word, word.ID)
+	if err == nil {
+		return &DuplicateWordError{Existing: existing}
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return nil
+}
+
+// checkFields returns every empty required field and every field that is too long
+func checkFields(word models.Word) []FieldError {
+	type field struct {
+		key       string
+		name      string
+		value     string
+		required  bool
+		maxLength int
+	}
+	fields := []field{
+		{"word", "word", word.Word, true, maxWordFieldLength},
+		{"translation", "translation", word.Translation, true, maxWordFieldLength},
+		{"category", "category", word.Category, false, maxWordFieldLength},
+		{"example_sentence", "example sentence", word.ExampleSentence, false, maxWordTextLength},
+		{"example_translation", "example translation", word.ExampleTranslation, false, maxWordTextLength},
+		{"note", "note", word.Note, false, maxWordTextLength},
+		{"part_of_speech", "part of speech", word.PartOfSpeech, false, maxPartOfSpeechLength},
+	}
+	for _, alternative := range word.Alternatives() {
+		fields = append(fields, field{"alternatives", "alternative translation", alternative, false, maxWordFieldLength})
+	}
+
+	var problems []FieldError
+	for _, field := range fields {
+		if field.required && field.value == "" {
+			problems = append(problems, FieldError{field.key, ProblemEmpty, fmt.Sprintf("%s must not be empty", field.name)})
+		}
+		if utf8.RuneCountInString(field.value) > field.maxLength {
+			problems = append(problems, FieldError{field.key, ProblemTooLong, fmt.Sprintf("%s must be at most %d characters", field.name, field.maxLength)})
+		}
+	}
+	return problems
+}
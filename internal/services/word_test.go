@@ -0,0 +1,118 @@
+package services_test
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"learning-cards/config"
+	"learning-cards/internal/media"
+	"learning-cards/internal/models"
+	"learning-cards/internal/repository"
+	"learning-cards/internal/services"
+
+	"gorm.io/gorm"
+)
+
+// pngHeader is enough of a PNG file for content sniffing
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00")
+
+func TestDeleteWordMediaFiles(t *testing.T) {
+	_, db, _ := setupSeedTest(t)
+	mediaConfig := config.MediaConfig{Directory: t.TempDir(), MaxSize: 1 << 10}
+	store, err := media.NewLocalStore(mediaConfig.Directory)
+	if err != nil {
+		t.Fatalf("failed to create media store: %v", err)
+	}
+	wordRepo := repository.NewWordRepository(db)
+	deckRepo := repository.NewDeckRepository(db, config.DeckConfig{SourceLanguage: "es", TargetLanguage: "de"})
+	words := services.NewWordService(wordRepo, deckRepo, store)
+	mediaService := services.NewMediaService(repository.NewMediaRepository(db), wordRepo, store, mediaConfig)
+
+	var created []models.Word
+	for _, text := range []string{"el perro", "el gato"} {
+		word, err := words.CreateWord(models.Word{Word: text, Translation: "das Tier", Category: "animals"})
+		if err != nil {
+			t.Fatalf("failed to create %q: %v", text, err)
+		}
+		created = append(created, word)
+	}
+	perro, gato := created[0], created[1]
+
+	// The first image is used by both words, the second one only by el perro
+	shared, err := mediaService.Upload(perro.ID, "animal.png", bytes.NewReader(pngHeader))
+	if err != nil {
+		t.Fatalf("failed to upload: %v", err)
+	}
+	if _, err := mediaService.Upload(gato.ID, "animal.png", bytes.NewReader(pngHeader)); err != nil {
+		t.Fatalf("failed to upload: %v", err)
+	}
+	own, err := mediaService.Upload(perro.ID, "perro.png", bytes.NewReader(append(pngHeader, 1)))
+	if err != nil {
+		t.Fatalf("failed to upload: %v", err)
+	}
+
+	if err := words.DeleteWord(perro.ID); err != nil {
+		t.Fatalf("failed to delete word: %v", err)
+	}
+	if exists, err := store.Exists(own.Name); err != nil || exists {
+		t.Fatalf("expected the unshared file to be deleted, exists: %v, err: %v", exists, err)
+	}
+	if exists, err := store.Exists(shared.Name); err != nil || !exists {
+		t.Fatalf("expected the shared file to be kept, exists: %v, err: %v", exists, err)
+	}
+
+	if err := words.DeleteWord(gato.ID); err != nil {
+		t.Fatalf("failed to delete word: %v", err)
+	}
+	if exists, err := store.Exists(shared.Name); err != nil || exists {
+		t.Fatalf("expected the file to be deleted with its last word, exists: %v, err: %v", exists, err)
+	}
+}
+
+func TestCreateRetiredWordAgain(t *testing.T) {
+	seeds, db, dir := setupSeedTest(t)
+	deckRepo := repository.NewDeckRepository(db, config.DeckConfig{SourceLanguage: "es", TargetLanguage: "de"})
+	words := services.NewWordService(repository.NewWordRepository(db), deckRepo, nil)
+
+	path := filepath.Join(dir, "colors.csv")
+	writeCSV(t, path, "word,translation,category", "rojo,rot,colors", "azul,blau,colors")
+	if _, err := seeds.SyncFile(path); err != nil {
+		t.Fatalf("SyncFile returned error: %v", err)
+	}
+	writeCSV(t, path, "word,translation,category", "rojo,rot,colors")
+	if _, err := seeds.SyncFile(path); err != nil {
+		t.Fatalf("SyncFile returned error: %v", err)
+	}
+
+	// The retired word does not block adding the word by hand
+	if _, err := words.FindWord("azul"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected the retired word not to be found, got %v", err)
+	}
+	created, err := words.CreateWord(models.Word{Word: "Azul", Translation: "blau", Category: "colors"})
+	if err != nil {
+		t.Fatalf("expected the retired word to be added again, got %v", err)
+	}
+	if _, err := words.CreateWord(models.Word{Word: "rojo", Translation: "rot", Category: "colors"}); err == nil {
+		t.Fatalf("expected a word that is not retired to still be a duplicate")
+	}
+
+	// Added back to the file, the word stays with the one added by hand
+	writeCSV(t, path, "word,translation,category", "rojo,rot,colors", "azul,blau,colors")
+	changes, err := seeds.SyncFile(path)
+	if err != nil {
+		t.Fatalf("SyncFile returned error: %v", err)
+	}
+	if changes != (repository.SeedChanges{Skipped: 1}) {
+		t.Fatalf("unexpected changes: %+v", changes)
+	}
+	var active int64
+	db.Model(&models.Word{}).Where("LOWER(word) = ? AND retired_at IS NULL", "azul").Count(&active)
+	if active != 1 {
+		t.Fatalf("expected one active azul, got %d", active)
+	}
+	if found, err := words.FindWord("azul"); err != nil || found.ID != created.ID {
+		t.Fatalf("expected the word added by hand, got %+v, %v", found, err)
+	}
+}
@@ -0,0 +1,245 @@
+package startup
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"learning-cards/config"
+	"learning-cards/internal/anki"
+	"learning-cards/internal/database"
+	"learning-cards/internal/media"
+	"learning-cards/internal/models"
+	"learning-cards/internal/repository"
+	"learning-cards/internal/scheduler"
+	"learning-cards/internal/services"
+	"os"
+	"strings"
+)
+
+// RunCommand runs one of the command line tools instead of the HTTP server.
+func RunCommand(name string, args []string) error {
+	switch name {
+	case "fit-fsrs":
+		return fitFSRS(args)
+	case "import-anki":
+		return importAnki(args)
+	case "export-anki":
+		return exportAnki(args)
+	case "set-admin":
+		return setAdmin(args)
+	case "set-password":
+		return setPassword(args)
+	default:
+		return fmt.Errorf("unknown command %q", name)
+	}
+}
+
+// fitFSRS fits FSRS weights on the review log and prints them in the format
+// expected by FSRS_WEIGHTS.
+func fitFSRS(args []string) error {
+	flags := flag.NewFlagSet("fit-fsrs", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	db, err := database.Open()
+	if err != nil {
+		return err
+	}
+	database.Migrate(db)
+
+	schedulerConfig := config.LoadSchedulerConfig()
+	initial := scheduler.DefaultFSRSWeights
+	if schedulerConfig.FSRSWeights != "" {
+		if initial, err = scheduler.ParseFSRSWeights(schedulerConfig.FSRSWeights); err != nil {
+			return err
+		}
+	}
+
+	service := services.NewUserWordService(repository.NewUserWordRepository(db, scheduler.Leitner{}))
+	fit, err := service.FitFSRSWeights(initial)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("fitted on %d reviews, log loss %.4f -> %.4f\n", fit.Reviews, fit.InitialLoss, fit.Loss)
+	fmt.Printf("FSRS_WEIGHTS=%s\n", scheduler.FormatFSRSWeights(fit.Weights))
+	return nil
+}
+
+// importAnki imports the notes of a .apkg file as words and prints the report
+func importAnki(args []string) error {
+	flags := flag.NewFlagSet("import-anki", flag.ContinueOnError)
+	username := flags.String("user", models.DefaultUsername, "user whose cards receive the review history")
+	history := flags.Bool("history", false, "replay the Anki review history on the cards of the imported words")
+	wordField := flags.String("word-field", "", "note field holding the word (default: the first field)")
+	translationField := flags.String("translation-field", "", "note field holding the translation (default: the second field)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: import-anki [flags] <file.apkg>")
+	}
+
+	collection, err := anki.ReadPackageFile(flags.Arg(0), config.LoadImportConfig().MaxCollectionSize)
+	if err != nil {
+		return err
+	}
+
+	db, err := database.Open()
+	if err != nil {
+		return err
+	}
+	database.Migrate(db)
+
+	sched, err := scheduler.New(config.LoadSchedulerConfig())
+	if err != nil {
+		return err
+	}
+	options := services.AnkiImportOptions{
+		WordField:        *wordField,
+		TranslationField: *translationField,
+		History:          *history,
+	}
+	if *history {
+		user, err := repository.NewUserRepository(db).GetUserByUsername(*username)
+		if err != nil {
+			return fmt.Errorf("user %q: %w", *username, err)
+		}
+		options.UserID = user.ID
+	}
+
+	mediaStore, err := media.NewLocalStore(config.LoadMediaConfig().Directory)
+	if err != nil {
+		return err
+	}
+	deckRepo := repository.NewDeckRepository(db, config.LoadDeckConfig())
+	wordService := services.NewWordService(repository.NewWordRepository(db), deckRepo, mediaStore)
+	service := services.NewImportService(wordService, repository.NewUserWordRepository(db, sched))
+	report, err := service.ImportAnki(collection, options)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// exportAnki writes the words of a category, or all of them, with the progress
+// of a user to an Anki package or text file
+func exportAnki(args []string) error {
+	flags := flag.NewFlagSet("export-anki", flag.ContinueOnError)
+	username := flags.String("user", models.DefaultUsername, "user whose progress is exported")
+	category := flags.String("category", "", "category to export together with its sub-decks (default: every word)")
+	format := flags.String("format", "apkg", "apkg, or tsv for a text file without progress")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: export-anki [flags] <output file>")
+	}
+	write := anki.WritePackage
+	switch *format {
+	case "apkg":
+	case "tsv":
+		write = anki.WriteText
+	default:
+		return fmt.Errorf("unknown format %q, want apkg or tsv", *format)
+	}
+
+	db, err := database.Open()
+	if err != nil {
+		return err
+	}
+	database.Migrate(db)
+
+	user, err := repository.NewUserRepository(db).GetUserByUsername(*username)
+	if err != nil {
+		return fmt.Errorf("user %q: %w", *username, err)
+	}
+	service := services.NewExportService(repository.NewWordRepository(db), repository.NewUserWordRepository(db, scheduler.Leitner{}))
+	collection, err := service.ExportAnki(user.ID, *category)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if err := write(file, collection); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("exported %d words to %s\n", len(collection.Notes), flags.Arg(0))
+	return nil
+}
+
+// setAdmin grants a user admin rights, or revokes them with -revoke
+func setAdmin(args []string) error {
+	flags := flag.NewFlagSet("set-admin", flag.ContinueOnError)
+	revoke := flags.Bool("revoke", false, "revoke admin rights instead of granting them")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: set-admin [-revoke] <username>")
+	}
+
+	db, err := database.Open()
+	if err != nil {
+		return err
+	}
+	database.Migrate(db)
+
+	username := flags.Arg(0)
+	if err := repository.NewUserRepository(db).SetAdmin(username, !*revoke); err != nil {
+		return fmt.Errorf("user %q: %w", username, err)
+	}
+	if *revoke {
+		fmt.Printf("revoked admin rights of %s\n", username)
+	} else {
+		fmt.Printf("granted admin rights to %s\n", username)
+	}
+	return nil
+}
+
+// setPassword sets the password of a user, read from the first line of the
+// standard input. This is how users without a password, like the default user
+// that owns the progress recorded before accounts existed, are claimed.
+func setPassword(args []string) error {
+	flags := flag.NewFlagSet("set-password", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: set-password <username> < password-file")
+	}
+
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	password = strings.TrimRight(password, "\r\n")
+
+	db, err := database.Open()
+	if err != nil {
+		return err
+	}
+	database.Migrate(db)
+
+	username := flags.Arg(0)
+	auth := services.NewAuthService(repository.NewUserRepository(db), repository.NewSessionRepository(db), config.LoadAuthConfig())
+	if err := auth.SetPassword(username, password); err != nil {
+		return fmt.Errorf("user %q: %w", username, err)
+	}
+	fmt.Printf("set the password of %s\n", username)
+	return nil
+}
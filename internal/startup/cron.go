@@ -1,18 +1,20 @@
 package startup
 
 import (
-	"errors"
 	"learning-cards/config"
 	"learning-cards/internal/handlers"
-	"learning-cards/internal/models"
+	"learning-cards/internal/services"
 	"log"
 	"os"
 
 	"github.com/robfig/cron/v3"
-	"gorm.io/gorm"
 )
 
-func setupCron(handler *handlers.UserWordHandler, db *gorm.DB, words []models.Word) error {
+func setupCron(
+	handler *handlers.UserWordHandler,
+	authService *services.AuthService,
+	seedService *services.SeedService,
+) error {
 	appCfg := config.LoadAppConfig()
 	c := cron.New()
 	hostname, err := os.Hostname()
@@ -29,8 +31,7 @@ func setupCron(handler *handlers.UserWordHandler, db *gorm.DB, words []models.Wo
 			}
 		}, "localhost")
 		addCron(c, "@every 1m", func() {
-			insertData(db, words)
-			log.Println("Inserted predefined words into the database.")
+			syncData(seedService)
 		}, "localhost")
 	} else {
 		addCron(c, "0 0 * * *", func() {
@@ -39,11 +40,19 @@ func setupCron(handler *handlers.UserWordHandler, db *gorm.DB, words []models.Wo
 			}
 		}, "production")
 		addCron(c, "0 1 * * *", func() {
-			insertData(db, words)
-			log.Println("Inserted predefined words into the database.")
+			syncData(seedService)
 		}, "production")
 	}
 
+	addCron(c, "30 2 * * *", func() {
+		removed, err := authService.DeleteExpiredSessions()
+		if err != nil {
+			log.Printf("Error deleting expired sessions: %v", err)
+			return
+		}
+		log.Printf("Deleted %d expired sessions.", removed)
+	}, "sessions")
+
 	c.Start()
 	return nil
 }
@@ -53,21 +62,3 @@ func addCron(c *cron.Cron, schedule string, job func(), env string) {
 		log.Fatalf("Error setting up cron job for %s: %v", env, err)
 	}
 }
-
-func insertData(db *gorm.DB, words []models.Word) {
-	for _, w := range words {
-		var existingWord models.Word
-		// Check if the word already exists in the database
-		err := db.Where("word = ?", w.Word).First(&existingWord).Error
-		if err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				// If the word does not exist, insert it
-				if err := db.Create(&w).Error; err != nil {
-					log.Printf("failed to insert word %s: %v", w.Word, err)
-				}
-			} else {
-				log.Printf("failed to check for existing word %s: %v", w.Word, err)
-			}
-		}
-	}
-}
@@ -5,9 +5,10 @@ import (
 	"learning-cards/config"
 	"learning-cards/internal/database"
 	"learning-cards/internal/handlers"
+	"learning-cards/internal/media"
 	"learning-cards/internal/repository"
+	"learning-cards/internal/scheduler"
 	"learning-cards/internal/services"
-	"learning-cards/internal/utils"
 	"log"
 
 	"github.com/gin-contrib/cors"
@@ -22,25 +23,53 @@ func Run() error {
 	}
 	database.Migrate(db)
 
-	userWordRepo := repository.NewUserWordRepository(db)
-	userWordService := services.NewUserWordService(userWordRepo)
-	userWordHandler := handlers.NewUserWordHandler(userWordService)
+	sched, err := scheduler.New(config.LoadSchedulerConfig())
+	if err != nil {
+		return err
+	}
+	log.Printf("using %s scheduler", sched.Name())
 
-	words, err := utils.ReadAllCSVs("data")
+	authConfig := config.LoadAuthConfig()
+	userRepo := repository.NewUserRepository(db)
+	userService := services.NewUserService(userRepo)
+	authService := services.NewAuthService(userRepo, repository.NewSessionRepository(db), authConfig)
+	userWordRepo := repository.NewUserWordRepository(db, sched)
+	userWordService := services.NewUserWordService(userWordRepo)
+	userWordHandler := handlers.NewUserWordHandler(userWordService, userService)
+	authHandler := handlers.NewAuthHandler(authService, userWordService, authConfig)
+	apiKeyService := services.NewAPIKeyService(repository.NewAPIKeyRepository(db))
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
+	deckRepo := repository.NewDeckRepository(db, config.LoadDeckConfig())
+	wordRepo := repository.NewWordRepository(db)
+	mediaConfig := config.LoadMediaConfig()
+	mediaStore, err := media.NewLocalStore(mediaConfig.Directory)
 	if err != nil {
-		log.Printf("warning loading words from CSVs: %v", err)
+		return err
+	}
+	wordService := services.NewWordService(wordRepo, deckRepo, mediaStore)
+	wordHandler := handlers.NewWordHandler(wordService)
+	importHandler := handlers.NewImportHandler(services.NewImportService(wordService, userWordRepo), config.LoadImportConfig())
+	exportHandler := handlers.NewExportHandler(services.NewExportService(wordRepo, userWordRepo))
+	mediaHandler := handlers.NewMediaHandler(services.NewMediaService(repository.NewMediaRepository(db), wordRepo, mediaStore, mediaConfig))
+	deckHandler := handlers.NewDeckHandler(services.NewDeckService(deckRepo))
+	backupHandler := handlers.NewBackupHandler(services.NewBackupService(repository.NewBackupRepository(db)))
+
+	seedService := services.NewSeedService(repository.NewSeedRepository(db, config.LoadDeckConfig()), dataDir)
+	syncData(seedService)
+	if err := watchData(seedService); err != nil {
+		log.Printf("warning watching %s, changes are picked up by cron only: %v", dataDir, err)
 	}
 
 	r := gin.Default()
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{appConfig.FrontendIP + ":3000"},
-		AllowMethods:     []string{"GET", "PUT", "OPTIONS"},
-		AllowHeaders:     []string{"Content-Type"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Content-Type", "Authorization"},
 		AllowCredentials: true,
 	}))
-	v1.RegisterRoutes(r, userWordHandler)
+	v1.RegisterRoutes(r, authService, apiKeyService, userService, authHandler, apiKeyHandler, userWordHandler, wordHandler, deckHandler, mediaHandler, importHandler, exportHandler, backupHandler)
 
-	if err := setupCron(userWordHandler, db, words); err != nil {
+	if err := setupCron(userWordHandler, authService, seedService); err != nil {
 		log.Println("cron setup warning:", err)
 	}
 
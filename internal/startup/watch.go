@@ -0,0 +1,114 @@
+package startup
+
+import (
+	"io/fs"
+	"learning-cards/internal/services"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// dataDir holds the CSV files words are seeded from
+const dataDir = "data"
+
+// watchDelay is how long the data directory has to be quiet before changed
+// files are synced. Editors and copies write a file in several steps.
+const watchDelay = 500 * time.Millisecond
+
+// syncData syncs the whole data directory. It runs at startup and from cron,
+// as a fallback for changes the watcher missed.
+func syncData(seedService *services.SeedService) {
+	changes, err := seedService.SyncAll()
+	if err != nil {
+		log.Printf("Error syncing words from %s: %v", seedService.Dir(), err)
+	}
+	log.Printf("Synced words from %s: %d inserted, %d updated, %d retired, %d skipped.",
+		seedService.Dir(), changes.Inserted, changes.Updated, changes.Retired, changes.Skipped)
+}
+
+// watchData syncs the CSV files of the data directory and its subdirectories
+// as soon as they change.
+func watchData(seedService *services.SeedService) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watchDirs(watcher, seedService.Dir()); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	go func() {
+		defer watcher.Close()
+		pending := make(map[string]struct{})
+		timer := time.NewTimer(watchDelay)
+		timer.Stop()
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Has(fsnotify.Create) {
+					if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+						if err := watchDirs(watcher, event.Name); err != nil {
+							log.Printf("Error watching %s: %v", event.Name, err)
+						}
+						// Files moved in together with the directory raise no events of their own
+						addCSVs(pending, event.Name)
+						timer.Reset(watchDelay)
+						continue
+					}
+				}
+				if filepath.Ext(event.Name) != ".csv" || event.Op == fsnotify.Chmod {
+					continue
+				}
+				pending[event.Name] = struct{}{}
+				timer.Reset(watchDelay)
+			case <-timer.C:
+				for path := range pending {
+					changes, err := seedService.SyncFile(path)
+					if err != nil {
+						log.Printf("Error syncing words from %s: %v", path, err)
+						continue
+					}
+					log.Printf("Synced words from %s: %d inserted, %d updated, %d retired, %d skipped.",
+						path, changes.Inserted, changes.Updated, changes.Retired, changes.Skipped)
+				}
+				clear(pending)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("Error watching %s: %v", seedService.Dir(), err)
+			}
+		}
+	}()
+	return nil
+}
+
+// watchDirs watches a directory and all directories below it
+func watchDirs(watcher *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return watcher.Add(path)
+		}
+		return nil
+	})
+}
+
+// addCSVs adds the CSV files below a directory to pending
+func addCSVs(pending map[string]struct{}, root string) {
+	filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() && filepath.Ext(path) == ".csv" {
+			pending[path] = struct{}{}
+		}
+		return nil
+	})
+}
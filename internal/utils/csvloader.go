@@ -1,12 +1,21 @@
 package utils
 
 import (
+	"bytes"
 	"encoding/csv"
+	"errors"
 	"fmt"
+	"io"
 	"learning-cards/internal/models"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
 )
 
 func ReadAllCSVs(dirPath string) ([]models.Word, error) {
@@ -17,15 +26,11 @@ func ReadAllCSVs(dirPath string) ([]models.Word, error) {
 			return err
 		}
 		if !info.IsDir() && filepath.Ext(path) == ".csv" {
-			records, err := ReadCSV(path) // ReadCSV returns [][]string
-			if err != nil {
-				return err
-			}
-			convertedWords, err := ConvertToWords(records) // Pass records to ConvertToWords
+			words, err := ReadCSVFile(path)
 			if err != nil {
 				return err
 			}
-			allWords = append(allWords, convertedWords...)
+			allWords = append(allWords, words...)
 		}
 		return nil
 	})
@@ -37,48 +42,276 @@ func ReadAllCSVs(dirPath string) ([]models.Word, error) {
 	return allWords, nil
 }
 
-func ReadCSV(filePath string) ([][]string, error) {
+// ReadCSVFile reads the words of a words CSV file. Errors name the file and
+// the line they were found on.
+func ReadCSVFile(path string) ([]models.Word, error) {
+	records, err := ReadCSV(path)
+	if err != nil {
+		return nil, err
+	}
+	words, err := convertToWords(records)
+	if err != nil {
+		return nil, inFile(path, err)
+	}
+	return words, nil
+}
+
+// ReadCSVRows reads the rows of a words CSV file like ConvertRecords.
+func ReadCSVRows(path string) ([]Row, error) {
+	records, err := ReadCSV(path)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := ConvertRecords(records)
+	if err != nil {
+		return nil, inFile(path, err)
+	}
+	return rows, nil
+}
+
+// ReadCSV reads every record of a CSV file like ReadRecords.
+func ReadCSV(filePath string) ([]Record, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, err
 	}
-	// check the error on file.close()
-	err = file.Close()
+	defer file.Close()
+
+	records, err := ReadRecords(file)
+	if err != nil {
+		return nil, inFile(filePath, err)
+	}
+	return records, nil
+}
+
+// LineError is an error found on a line of a CSV. File is empty for CSVs
+// that were not read from a file.
+type LineError struct {
+	File string
+	Line int
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+	}
+	return fmt.Sprintf("%s:%d: %v", e.File, e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// inFile adds the name of the file to an error
+func inFile(path string, err error) error {
+	var lineErr *LineError
+	if errors.As(err, &lineErr) {
+		lineErr.File = path
+		return err
+	}
+	return fmt.Errorf("%s: %w", path, err)
+}
+
+// ErrFieldCount is the error of a record whose number of fields differs from the header
+var ErrFieldCount = errors.New("wrong number of fields")
+
+// Record is a record of a CSV file together with the line it starts on
+type Record struct {
+	Line   int
+	Fields []string
+}
+
+// Row is a record of a words CSV converted to a word, with the value of its
+// key column. Err is set instead of Word when the record cannot be converted.
+type Row struct {
+	Line int
+	Key  string
+	Word models.Word
+	Err  error
+}
+
+// delimiters are the field separators a CSV may use, preferred in this order
+// when the header row contains as many of each.
+var delimiters = []rune{',', ';', '\t'}
+
+// ReadRecords reads every record of a CSV. The encoding and the delimiter are
+// detected, see decodeCSV and detectDelimiter. Records may have any number of
+// fields, so that they can be reported one by one.
+func ReadRecords(r io.Reader) ([]Record, error) {
+	content, err := decodeCSV(r)
 	if err != nil {
 		return nil, err
 	}
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.Comma = detectDelimiter(content)
+	reader.FieldsPerRecord = -1
 
-	reader := csv.NewReader(file)
+	var records []Record
+	for {
+		fields, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, &LineError{Line: parseErr.Line, Err: parseErr.Err}
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		records = append(records, Record{Line: line, Fields: fields})
+	}
+}
 
-	records, err := reader.ReadAll()
+// decodeCSV returns the content of a CSV as UTF-8. A byte order mark selects
+// UTF-8 or UTF-16 and is removed. Content without one that is not valid UTF-8
+// is taken as Windows-1252, which Excel saves CSV files in.
+func decodeCSV(r io.Reader) ([]byte, error) {
+	content, err := io.ReadAll(transform.NewReader(r, unicode.BOMOverride(transform.Nop)))
 	if err != nil {
 		return nil, err
 	}
+	if utf8.Valid(content) {
+		return content, nil
+	}
+	return charmap.Windows1252.NewDecoder().Bytes(content)
+}
 
-	return records, nil
+// detectDelimiter returns the delimiter found most often in the header row,
+// which only holds column names.
+func detectDelimiter(content []byte) rune {
+	header, _, _ := bytes.Cut(content, []byte("\n"))
+	delimiter, most := delimiters[0], 0
+	for _, candidate := range delimiters {
+		if count := bytes.Count(header, []byte(string(candidate))); count > most {
+			delimiter, most = candidate, count
+		}
+	}
+	return delimiter
 }
 
+// Columns of a words CSV, found by the header row. Only word, translation and
+// category are required, the order of the columns does not matter.
+const (
+	ColumnWord               = "word"
+	ColumnTranslation        = "translation"
+	ColumnCategory           = "category"
+	ColumnExampleSentence    = "example_sentence"
+	ColumnExampleTranslation = "example_translation"
+	ColumnNote               = "note"
+	ColumnPartOfSpeech       = "part_of_speech"
+	// ColumnKey identifies a seeded word within its file, so that its text can
+	// change without losing progress
+	ColumnKey = "key"
+)
+
+var requiredColumns = []string{ColumnWord, ColumnTranslation, ColumnCategory}
+
+// ConvertToWords converts the records of a words CSV, the first of which is
+// the header row. Columns with other names are ignored.
 func ConvertToWords(records [][]string) ([]models.Word, error) {
+	numbered := make([]Record, len(records))
+	for i, fields := range records {
+		numbered[i] = Record{Line: i + 1, Fields: fields}
+	}
+	return convertToWords(numbered)
+}
+
+// convertToWords converts records like ConvertRecords, but stops at the first
+// record that cannot be converted.
+func convertToWords(records []Record) ([]models.Word, error) {
+	rows, err := ConvertRecords(records)
+	if err != nil {
+		return nil, err
+	}
+
 	var words []models.Word
+	for _, row := range rows {
+		if row.Err != nil {
+			return nil, &LineError{Line: row.Line, Err: row.Err}
+		}
+		words = append(words, row.Word)
+	}
+	return words, nil
+}
 
-	for i, record := range records {
-		if i == 0 {
-			continue
+// ConvertRecords converts the records of a words CSV like ConvertToWords, but
+// keeps going after a record that cannot be converted. Only a header without
+// the required columns is an error.
+func ConvertRecords(records []Record) ([]Row, error) {
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	header := records[0]
+	columns := make(map[string]int, len(header.Fields))
+	for i, name := range header.Fields {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range requiredColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, &LineError{Line: header.Line, Err: fmt.Errorf("header is missing column %q", name)}
 		}
+	}
 
-		if len(record) != 3 {
-			return nil, fmt.Errorf("record on line %d: wrong number of fields, got %d, want 3", i+1, len(record))
+	rows := make([]Row, 0, len(records)-1)
+	for _, record := range records[1:] {
+		if len(record.Fields) != len(header.Fields) {
+			rows = append(rows, Row{
+				Line: record.Line,
+				Err:  fmt.Errorf("%w, got %d, want %d", ErrFieldCount, len(record.Fields), len(header.Fields)),
+			})
+			continue
 		}
+		row := Row{Line: record.Line, Word: convertRecord(columns, record.Fields)}
+		if column, ok := columns[ColumnKey]; ok {
+			row.Key = strings.TrimSpace(record.Fields[column])
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
 
-		word := models.Word{
-			Word:        record[0],
-			Translation: record[1],
-			Category:    record[2],
-			CreatedAt:   time.Now(),
+func convertRecord(columns map[string]int, record []string) models.Word {
+	field := func(name string) string {
+		if column, ok := columns[name]; ok {
+			return strings.TrimSpace(record[column])
 		}
+		return ""
+	}
 
-		words = append(words, word)
+	translations := SplitTranslations(field(ColumnTranslation))
+	if len(translations) == 0 {
+		translations = []string{""}
+	}
+	word := models.Word{
+		Word:               field(ColumnWord),
+		Category:           field(ColumnCategory),
+		Gender:             ParseGender(field(ColumnWord)),
+		TranslationGender:  ParseGender(translations[0]),
+		ExampleSentence:    field(ColumnExampleSentence),
+		ExampleTranslation: field(ColumnExampleTranslation),
+		Note:               field(ColumnNote),
+		PartOfSpeech:       strings.ToLower(field(ColumnPartOfSpeech)),
+		CreatedAt:          time.Now(),
 	}
+	word.SetTranslations(translations[0], translations[1:])
+	return word
+}
 
-	return words, nil
+// TranslationSeparator separates the accepted translations of a word in the
+// translation column, the first one being the primary translation.
+const TranslationSeparator = "|"
+
+// SplitTranslations splits a translation column into its trimmed, non-empty translations.
+func SplitTranslations(column string) []string {
+	var translations []string
+	for _, translation := range strings.Split(column, TranslationSeparator) {
+		if translation = strings.TrimSpace(translation); translation != "" {
+			translations = append(translations, translation)
+		}
+	}
+	return translations
 }
@@ -0,0 +1,134 @@
+package utils_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"learning-cards/internal/models"
+	"learning-cards/internal/utils"
+)
+
+func TestConvertToWordsByHeader(t *testing.T) {
+	records := [][]string{
+		{"Category", "word", "translation", "note", "example_sentence", "example_translation", "part_of_speech", "source"},
+		{"food", "la naranja", " die Orange | die Apfelsine ", "", "Me gusta la naranja.", "Ich mag die Orange.", "Noun", "book"},
+		{"colors", "rojo", "rot", "also: colorado", "", "", "", ""},
+	}
+	words, err := utils.ConvertToWords(records)
+	if err != nil {
+		t.Fatalf("ConvertToWords returned error: %v", err)
+	}
+	if len(words) != 2 {
+		t.Fatalf("expected 2 words, got %d", len(words))
+	}
+	naranja := words[0]
+	if naranja.Word != "la naranja" || naranja.Category != "food" || naranja.Translation != "die Orange" {
+		t.Fatalf("unexpected word: %+v", naranja)
+	}
+	if got := naranja.Alternatives(); len(got) != 1 || got[0] != "die Apfelsine" {
+		t.Fatalf("expected one alternative translation, got %v", got)
+	}
+	if naranja.ExampleSentence != "Me gusta la naranja." || naranja.ExampleTranslation != "Ich mag die Orange." || naranja.PartOfSpeech != "noun" {
+		t.Fatalf("unexpected optional fields: %+v", naranja)
+	}
+	if naranja.Gender != models.GenderFeminine || naranja.TranslationGender != models.GenderFeminine {
+		t.Fatalf("unexpected genders: %q, %q", naranja.Gender, naranja.TranslationGender)
+	}
+	if words[1].Note != "also: colorado" || words[1].ExampleSentence != "" {
+		t.Fatalf("unexpected optional fields: %+v", words[1])
+	}
+}
+
+func TestConvertToWordsRequiresColumns(t *testing.T) {
+	_, err := utils.ConvertToWords([][]string{{"word", "translation"}, {"rojo", "rot"}})
+	if err == nil || !strings.Contains(err.Error(), `"category"`) {
+		t.Fatalf("expected missing category column error, got %v", err)
+	}
+	_, err = utils.ConvertToWords([][]string{{"word", "translation", "category"}, {"rojo", "rot"}})
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("expected wrong number of fields error on line 2, got %v", err)
+	}
+}
+
+func TestConvertRecordsKeepsGoing(t *testing.T) {
+	records, err := utils.ReadRecords(strings.NewReader("word,translation,category\nrojo,rot\n\"el\nrío\",der Fluss,nature\n"))
+	if err != nil {
+		t.Fatalf("ReadRecords returned error: %v", err)
+	}
+	rows, err := utils.ConvertRecords(records)
+	if err != nil {
+		t.Fatalf("ConvertRecords returned error: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(rows))
+	}
+	if rows[0].Line != 2 || !errors.Is(rows[0].Err, utils.ErrFieldCount) {
+		t.Fatalf("expected a field count error on line 2, got %+v", rows[0])
+	}
+	if rows[1].Line != 3 || rows[1].Err != nil || rows[1].Word.Word != "el\nrío" {
+		t.Fatalf("expected the word on line 3, got %+v", rows[1])
+	}
+}
+
+func TestReadRecordsDetectsFormat(t *testing.T) {
+	utf16 := []byte{0xFF, 0xFE}
+	for _, r := range "word\ttranslation\tcategory\nel río\tder Fluss\tnature\n" {
+		utf16 = append(utf16, byte(r), byte(r>>8))
+	}
+	inputs := map[string][]byte{
+		"comma":        []byte("word,translation,category\nel río,der Fluss,nature\n"),
+		"semicolon":    []byte("word;translation;category\r\nel río;der Fluss;nature\r\n"),
+		"tab":          []byte("word\ttranslation\tcategory\nel río\tder Fluss\tnature\n"),
+		"bom":          []byte("\xEF\xBB\xBFword;translation;category\nel río;der Fluss;nature\n"),
+		"utf-16":       utf16,
+		"windows-1252": []byte("category;word;translation\nnature;el r\xEDo;der Fluss\n"),
+	}
+	for name, input := range inputs {
+		records, err := utils.ReadRecords(bytes.NewReader(input))
+		if err != nil {
+			t.Fatalf("%s: ReadRecords returned error: %v", name, err)
+		}
+		rows, err := utils.ConvertRecords(records)
+		if err != nil {
+			t.Fatalf("%s: ConvertRecords returned error: %v", name, err)
+		}
+		if len(rows) != 1 || rows[0].Err != nil {
+			t.Fatalf("%s: expected one row, got %+v", name, rows)
+		}
+		if word := rows[0].Word; word.Word != "el río" || word.Translation != "der Fluss" || word.Category != "nature" {
+			t.Fatalf("%s: unexpected word: %+v", name, word)
+		}
+	}
+}
+
+func TestReadCSVFileErrorsNameFileAndLine(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"fields.csv": "word,translation,category\nrojo,rot,colors\nazul,blau\n",
+		"header.csv": "word,translation\nrojo,rot\n",
+		"quotes.csv": "word,translation,category\nrojo,rot,colors\n\"azul\"x,blau,colors\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	expected := map[string]string{"fields.csv": ":3: ", "header.csv": ":1: ", "quotes.csv": ":3: "}
+	for name, location := range expected {
+		path := filepath.Join(dir, name)
+		_, err := utils.ReadCSVFile(path)
+		var lineErr *utils.LineError
+		if !errors.As(err, &lineErr) || lineErr.File != path || !strings.Contains(err.Error(), path+location) {
+			t.Fatalf("%s: expected an error on %s%s, got %v", name, path, location, err)
+		}
+	}
+	if _, err := utils.ReadCSVFile(filepath.Join(dir, "fields.csv")); !errors.Is(err, utils.ErrFieldCount) {
+		t.Fatalf("expected ErrFieldCount, got %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"learning-cards/internal/models"
+	"strings"
+)
+
+// articleGenders maps the Spanish and German articles that give away the
+// gender of a noun. "die" is taken as feminine as the words are singular.
+var articleGenders = map[string]models.Gender{
+	"el": models.GenderMasculine, "los": models.GenderMasculine,
+	"un": models.GenderMasculine, "unos": models.GenderMasculine,
+	"la": models.GenderFeminine, "las": models.GenderFeminine,
+	"una": models.GenderFeminine, "unas": models.GenderFeminine,
+	"der": models.GenderMasculine, "die": models.GenderFeminine,
+	"das": models.GenderNeuter, "eine": models.GenderFeminine,
+}
+
+// SplitArticle splits a noun like "der Hund" into its leading article and the
+// rest. The article is empty when the text does not start with one.
+func SplitArticle(text string) (string, string) {
+	text = strings.TrimSpace(text)
+	first, rest, found := strings.Cut(text, " ")
+	if _, ok := articleGenders[strings.ToLower(first)]; found && ok {
+		return first, strings.TrimSpace(rest)
+	}
+	return "", text
+}
+
+// ParseGender returns the gender given away by the leading article of text,
+// or an empty gender.
+func ParseGender(text string) models.Gender {
+	article, _ := SplitArticle(text)
+	return ArticleGender(article)
+}
+
+// ArticleGender returns the gender of an article, or an empty gender.
+func ArticleGender(article string) models.Gender {
+	return articleGenders[strings.ToLower(strings.TrimSpace(article))]
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Slugify turns a name into a lowercase ASCII identifier, so that names that
+// only differ in case, accents, spacing or punctuation share the same slug.
+func Slugify(name string) string {
+	stripped, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), name)
+	if err != nil {
+		stripped = name
+	}
+
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(stripped) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// DeckPathSeparator separates the levels of nested decks, as in business::meetings.
+const DeckPathSeparator = "::"
+
+// SplitDeckPath splits a deck path into its trimmed, non-empty levels.
+func SplitDeckPath(path string) []string {
+	var segments []string
+	for _, segment := range strings.Split(path, DeckPathSeparator) {
+		if segment = strings.TrimSpace(segment); segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// SlugifyPath slugifies every level of a deck path.
+func SlugifyPath(path string) string {
+	segments := SplitDeckPath(path)
+	for i, segment := range segments {
+		segments[i] = Slugify(segment)
+	}
+	return strings.Join(segments, DeckPathSeparator)
+}